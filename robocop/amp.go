@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// ampSelector finds a page's AMP version. We crawl these like ordinary
+// links, so a broken one shows up in the report with the element amphtml.
+const ampSelector = `link[rel~="amphtml" i][href]`
+
+// ampPage matches the html element of an AMP page.
+const ampPage = "html[amp], html[⚡]"
+
+// ampCheck checks the links between pages and their AMP versions. A page
+// doesn't have to have an AMP version, but if it points to one with
+// rel=amphtml, the AMP page should point back with rel=canonical.
+//
+// Whether the canonical page points back to the same AMP page can only be
+// checked once we've seen both, so that's left to Finish.
+type ampCheck struct {
+	sync.Mutex
+	amphtml   map[string]string
+	canonical map[string]string
+}
+
+func (*ampCheck) Name() string {
+	return "amp"
+}
+
+func (a *ampCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	// Error pages don't have to link anywhere.
+	if page.StatusCode >= 300 {
+		return nil
+	}
+	source := page.Request.URL.String()
+
+	if !isAMPPage(doc) {
+		href, ok := doc.Find(ampSelector).First().Attr("href")
+		if !ok {
+			return nil
+		}
+		a.Lock()
+		a.amphtml[source] = page.Request.AbsoluteURL(href)
+		a.Unlock()
+		return nil
+	}
+
+	href, ok := doc.Find(`link[rel~="canonical" i][href]`).First().Attr("href")
+	if !ok {
+		return []Finding{{
+			Page:   source,
+			Link:   source,
+			Status: "missing-amp-canonical",
+		}}
+	}
+	a.Lock()
+	a.canonical[source] = page.Request.AbsoluteURL(href)
+	a.Unlock()
+	return nil
+}
+
+// isAMPPage is true if doc is an AMP page rather than the canonical one.
+func isAMPPage(doc *goquery.Document) bool {
+	// We're usually given the html element itself, rather than the whole
+	// document.
+	return doc.Selection.Is(ampPage) || doc.Find(ampPage).Length() > 0
+}
+
+// ampRequiredCheck reports the pages which don't point to an AMP version,
+// for -require-amphtml, on sites where every page should have one.
+type ampRequiredCheck struct{}
+
+func (ampRequiredCheck) Name() string {
+	return "amp-required"
+}
+
+func (ampRequiredCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	if page.StatusCode >= 300 || isAMPPage(doc) || doc.Find(ampSelector).Length() > 0 {
+		return nil
+	}
+	source := page.Request.URL.String()
+	return []Finding{{
+		Page:   source,
+		Link:   source,
+		Status: "missing-amphtml",
+	}}
+}
+
+// Finish returns a finding for each AMP page whose canonical page we
+// crawled, but which points to some other AMP page, or none at all.
+func (a *ampCheck) Finish() []Finding {
+	a.Lock()
+	defer a.Unlock()
+
+	findings := []Finding{}
+	for ampPage, canonical := range a.canonical {
+		amphtml, crawled := a.amphtml[canonical]
+		if !crawled || amphtml == ampPage {
+			continue
+		}
+		findings = append(findings, Finding{
+			Page:   ampPage,
+			Link:   canonical,
+			Status: "amp-canonical-mismatch",
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Page < findings[j].Page
+	})
+	return findings
+}
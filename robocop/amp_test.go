@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func TestAMPChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		check Check
+		want  string
+	}{
+		{
+			name:  "no AMP version",
+			body:  `<html><head></head><body></body></html>`,
+			check: &ampCheck{amphtml: map[string]string{}, canonical: map[string]string{}},
+		},
+		{
+			name:  "no AMP version when we require one",
+			body:  `<html><head></head><body></body></html>`,
+			check: ampRequiredCheck{},
+			want:  "missing-amphtml",
+		},
+		{
+			name:  "an AMP version when we require one",
+			body:  `<html><head><link rel="amphtml" href="/amp/page"></head><body></body></html>`,
+			check: ampRequiredCheck{},
+		},
+		{
+			name:  "an AMP page doesn't need one",
+			body:  `<html amp><head><link rel="canonical" href="/page"></head><body></body></html>`,
+			check: ampRequiredCheck{},
+		},
+		{
+			name:  "an AMP page without a canonical page",
+			body:  `<html amp><head></head><body></body></html>`,
+			check: &ampCheck{amphtml: map[string]string{}, canonical: map[string]string{}},
+			want:  "missing-amp-canonical",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, doc := testPage(t, "https://example.com/page", tt.body)
+			findings := tt.check.Run(page, doc)
+			got := ""
+			if len(findings) > 0 {
+				got = findings[0].Status
+			}
+			if got != tt.want || len(findings) > 1 {
+				t.Errorf("got findings %v, want %q", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAMP(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="/plain">Plain</a>
+			<a href="/article">Article</a>
+			<a href="/other">Other</a>`),
+		"http://example.com/plain":   html(""),
+		"http://example.com/article": html(`<link rel="amphtml" href="/amp/article">`),
+		"http://example.com/other":   html(`<link rel="amphtml" href="/amp/other">`),
+		"http://example.com/amp/other": {
+			status:      200,
+			contentType: "text/html",
+			body:        `<html amp><head><link rel="canonical" href="/article"></head><body></body></html>`,
+		},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "declared AMP versions",
+			args: []string{"-check-amp"},
+			want: []string{
+				"http://example.com/amp/article 404",
+				"http://example.com/article amp-canonical-mismatch",
+			},
+		},
+		{
+			name: "every page should have one",
+			args: []string{"-check-amp", "-require-amphtml"},
+			want: []string{
+				"http://example.com/ missing-amphtml",
+				"http://example.com/amp/article 404",
+				"http://example.com/article amp-canonical-mismatch",
+				"http://example.com/plain missing-amphtml",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			got := reported(rows)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got report %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got report %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// anchorTextCheck flags links whose text is a URL, but not the one they
+// link to, e.g. <a href="https://example.com/b">https://example.com/a</a>.
+// That's usually a copy and paste mistake. Only the host and path have to
+// match, so http text on an https link is fine.
+type anchorTextCheck struct{}
+
+func (anchorTextCheck) Name() string {
+	return "mismatched-anchor"
+}
+
+func (anchorTextCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	source := page.Request.URL.String()
+
+	findings := []Finding{}
+	doc.Find(linkSelector).Each(func(_ int, s *goquery.Selection) {
+		text, ok := textURL(s.Text())
+		if !ok {
+			return
+		}
+		href, _ := s.Attr("href")
+		link, err := url.Parse(page.Request.AbsoluteURL(href))
+		if err != nil || link.Host == "" {
+			return
+		}
+		if !sameHostAndPath(text, link) {
+			findings = append(findings, Finding{
+				Page:   source,
+				Link:   link.String(),
+				Status: "mismatched-anchor",
+			})
+		}
+	})
+	return findings
+}
+
+// textURL parses link text which looks like a URL: it starts with http://,
+// https:// or www. and has no spaces.
+func textURL(text string) (*url.URL, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text, " \t\n") {
+		return nil, false
+	}
+	lower := strings.ToLower(text)
+	if strings.HasPrefix(lower, "www.") {
+		text = "http://" + text
+	} else if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return nil, false
+	}
+	u, err := url.Parse(text)
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+func sameHostAndPath(a, b *url.URL) bool {
+	return strings.EqualFold(a.Host, b.Host) &&
+		strings.TrimSuffix(a.EscapedPath(), "/") == strings.TrimSuffix(b.EscapedPath(), "/")
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestAnchorTextCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want bool
+	}{
+		{name: "the same URL", link: `<a href="https://example.com/a">https://example.com/a</a>`},
+		{name: "http text on an https link", link: `<a href="https://example.com/a/">http://example.com/a</a>`},
+		{name: "www.", link: `<a href="https://www.example.com/a">www.example.com/a</a>`},
+		{name: "the host's case", link: `<a href="https://example.com/a">https://EXAMPLE.com/a</a>`},
+		{name: "a relative link", link: `<a href="/a">https://example.com/a</a>`},
+		{name: "not a URL", link: `<a href="https://example.com/b">Read about a</a>`},
+		{name: "more than a URL", link: `<a href="https://example.com/b">see https://example.com/a</a>`},
+		{name: "not a web link", link: `<a href="mailto:me@example.com">https://example.com/</a>`},
+		{name: "another path", link: `<a href="https://example.com/b">https://example.com/a</a>`, want: true},
+		{name: "another host", link: `<a href="https://example.org/a">https://example.com/a</a>`, want: true},
+		{name: "a relative link elsewhere", link: `<a href="/b">https://example.com/a</a>`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, doc := testPage(t, "https://example.com/page", tt.link)
+			findings := anchorTextCheck{}.Run(page, doc)
+			if got := len(findings) > 0; got != tt.want {
+				t.Errorf("%s: got mismatched %v, want %v (%v)", tt.link, got, tt.want, findings)
+			}
+		})
+	}
+}
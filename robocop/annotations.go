@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printAnnotations writes a GitHub Actions workflow command for every row
+// which is an error or a warning, for -github-annotations, so that broken
+// links show up in the summary of the run.
+func printAnnotations(w io.Writer, rows linkReport) error {
+	for _, row := range rows {
+		command := row[severityColumn]
+		if command != severityError && command != severityWarning {
+			continue
+		}
+		// With -list-referrers the sources have been joined together.
+		for _, source := range strings.Split(row[sourceColumn], "\n") {
+			message := fmt.Sprintf("%s is %s on %s", row[linkColumn], row[statusColumn], source)
+			if _, err := fmt.Fprintf(w, "::%s title=%s::%s\n", command, escapeProperty(row[statusColumn]), escapeData(message)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// escapeData escapes the message of a workflow command the way the
+// Actions toolkit does.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property of a workflow command, which can't
+// have colons or commas in it either.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintAnnotations(t *testing.T) {
+	row := func(source, link, status, severity string) []string {
+		r := reportRow(source, link, status)
+		r[severityColumn] = severity
+		return r
+	}
+
+	tests := []struct {
+		name string
+		rows linkReport
+		want string
+	}{
+		{name: "nothing to report"},
+		{
+			name: "errors and warnings",
+			rows: linkReport{
+				row("http://example.com/", "http://example.com/gone", "404", severityError),
+				row("http://example.com/", "http://example.com/fine", "200", severityOK),
+				row("http://example.com/", "http://example.com/old", "301", severityWarning),
+			},
+			want: "::error title=404::http://example.com/gone is 404 on http://example.com/\n" +
+				"::warning title=301::http://example.com/old is 301 on http://example.com/\n",
+		},
+		{
+			name: "one for each referrer",
+			rows: linkReport{
+				row("http://example.com/a\nhttp://example.com/b", "http://example.com/gone", "404", severityError),
+			},
+			want: "::error title=404::http://example.com/gone is 404 on http://example.com/a\n" +
+				"::error title=404::http://example.com/gone is 404 on http://example.com/b\n",
+		},
+		{
+			name: "escaped",
+			rows: linkReport{
+				row("http://example.com/", "http://example.com/100%", "bad:status,really", severityError),
+			},
+			want: "::error title=bad%3Astatus%2Creally::http://example.com/100%25 is bad:status,really on http://example.com/\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := printAnnotations(&out, tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// isAssumedOK is true if host is one of -assume-ok-hosts, or a subdomain
+// of one, so that links to it aren't checked at all. As with
+// -allow-insecure-hosts, a host can be given with or without its port.
+func isAssumedOK(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	for _, ok := range hosts {
+		ok = strings.ToLower(ok)
+		if host == ok || name == ok || strings.HasSuffix(name, "."+ok) {
+			return true
+		}
+	}
+	return false
+}
+
+// notChecked is true for the statuses of links we deliberately didn't
+// check. Like 200s, they're only in the report with -all.
+func notChecked(status string) bool {
+	return status == "skipped" || status == "assumed-ok"
+}
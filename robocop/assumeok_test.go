@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsAssumedOK(t *testing.T) {
+	hosts := []string{"Twitter.com", "cdn.example.com:8443"}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "twitter.com", want: true},
+		{host: "TWITTER.COM", want: true},
+		{host: "mobile.twitter.com", want: true},
+		{host: "twitter.com:443", want: true},
+		{host: "nottwitter.com"},
+		{host: "twitter.com.evil.test"},
+		{host: "cdn.example.com:8443", want: true},
+		{host: "cdn.example.com"},
+		{host: "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := isAssumedOK(tt.host, hosts); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssumeOKHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "checked",
+			want: []string{"http://other.test/gone 404", "https://twitter.com/gone 404"},
+		},
+		{
+			name: "assumed ok",
+			args: []string{"-assume-ok-hosts=twitter.com"},
+			want: []string{"http://other.test/gone 404"},
+		},
+		{
+			name: "with -all",
+			args: []string{"-assume-ok-hosts=twitter.com", "-all"},
+			want: []string{"http://other.test/gone 404", "https://twitter.com/gone assumed-ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newStubSite(map[string]stubPage{
+				"http://example.com/": html(`<a href="https://twitter.com/gone">Tweet</a><a href="http://other.test/gone">Gone</a>`),
+			})
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if n := site.count("HEAD", "https://twitter.com/gone"); len(tt.args) > 0 && n != 0 {
+				t.Errorf("got %d requests for an assumed ok link, want none", n)
+			}
+		})
+	}
+}
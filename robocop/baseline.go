@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// baselineEntry is a row of the report, as saved by -baseline-generate.
+type baselineEntry struct {
+	Source   string `json:"source"`
+	Link     string `json:"link"`
+	Status   string `json:"status"`
+	Accepted bool   `json:"accepted"`
+}
+
+type baselineFile struct {
+	Generated time.Time       `json:"generated"`
+	Meta      *runMeta        `json:"meta,omitempty"`
+	Entries   []baselineEntry `json:"entries"`
+}
+
+// baseline implements flag.Value for -baseline. Set reads the file, and
+// the accepted rows in it are left out of the report, so that a site with
+// known link debt can adopt the auditor and only hear about new problems.
+// A row is only accepted while its status stays the same.
+type baseline map[[3]string]bool
+
+func (b baseline) String() string {
+	return ""
+}
+
+func (b baseline) Set(path string) error {
+	file, err := readBaselineFile(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range file.Entries {
+		if entry.Accepted {
+			b[[3]string{entry.Source, entry.Link, entry.Status}] = true
+		}
+	}
+	return nil
+}
+
+func readBaselineFile(path string) (baselineFile, error) {
+	var file baselineFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+	err = json.Unmarshal(data, &file)
+	return file, err
+}
+
+func (b baseline) accepts(row []string) bool {
+	return b[[3]string{row[sourceColumn], row[linkColumn], row[statusColumn]}]
+}
+
+// writeBaseline saves every row of the report to path. With accept, the
+// rows are marked as accepted, so that a run with -baseline=path ignores
+// them. meta, if there is any, goes at the top.
+func writeBaseline(path string, rows linkReport, accept bool, meta *runMeta) error {
+	file := baselineFile{Generated: time.Now().UTC(), Meta: meta, Entries: []baselineEntry{}}
+	for _, row := range rows {
+		// With -list-referrers the sources have been joined together.
+		for _, source := range strings.Split(row[sourceColumn], "\n") {
+			file.Entries = append(file.Entries, baselineEntry{
+				Source:   source,
+				Link:     row[linkColumn],
+				Status:   row[statusColumn],
+				Accepted: accept,
+			})
+		}
+	}
+
+	// Keep the file stable between runs, so that it diffs nicely.
+	sort.Slice(file.Entries, func(i, j int) bool {
+		a, b := file.Entries[i], file.Entries[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Link < b.Link
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0666)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	saved := linkReport{
+		reportRow("http://example.com/", "http://example.com/gone", "404"),
+		// -list-referrers joins the sources together.
+		reportRow("http://example.com/a\nhttp://example.com/b", "http://other.test/", "500"),
+	}
+
+	tests := []struct {
+		name   string
+		accept bool
+		row    []string
+		want   bool
+	}{
+		{name: "the same row", accept: true, row: reportRow("http://example.com/", "http://example.com/gone", "404"), want: true},
+		{name: "not accepted", row: reportRow("http://example.com/", "http://example.com/gone", "404")},
+		{name: "another status", accept: true, row: reportRow("http://example.com/", "http://example.com/gone", "410")},
+		{name: "another page", accept: true, row: reportRow("http://example.com/c", "http://example.com/gone", "404")},
+		{name: "one of the referrers", accept: true, row: reportRow("http://example.com/b", "http://other.test/", "500"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "baseline.json")
+			if err := writeBaseline(path, saved, tt.accept, nil); err != nil {
+				t.Fatal(err)
+			}
+			b := baseline{}
+			if err := b.Set(path); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.accepts(tt.row); got != tt.want {
+				t.Errorf("got accepted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
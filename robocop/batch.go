@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// headBatch holds on to the external links we find and hands them to send
+// in batches, for -external-batch-size and -external-batch-interval, rather
+// than HEADing each one as soon as we see it. A batch goes out when it's
+// full or when the interval is up, whichever comes first.
+type headBatch struct {
+	sync.Mutex
+	size  int
+	links []string
+	seen  map[string]bool
+	send  func(links []string)
+	stop  chan bool
+	done  chan bool
+}
+
+func newHeadBatch(size int, interval time.Duration, send func(links []string)) *headBatch {
+	b := &headBatch{
+		size: size,
+		seen: map[string]bool{},
+		send: send,
+		stop: make(chan bool),
+		done: make(chan bool),
+	}
+	if interval <= 0 {
+		close(b.done)
+		return b
+	}
+
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.flush()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	return b
+}
+
+func (b *headBatch) add(link string) {
+	b.Lock()
+	defer b.Unlock()
+	if b.seen[link] {
+		return
+	}
+	b.seen[link] = true
+	b.links = append(b.links, link)
+	if b.size > 0 && len(b.links) >= b.size {
+		b.sendLocked()
+	}
+}
+
+// flush sends whatever is waiting, and is false if there was nothing.
+func (b *headBatch) flush() bool {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.links) == 0 {
+		return false
+	}
+	b.sendLocked()
+	return true
+}
+
+// sendLocked holds the lock while sending, so that once flush returns
+// false, every link we were given has been sent.
+func (b *headBatch) sendLocked() {
+	links := b.links
+	b.links = nil
+	b.send(links)
+}
+
+// close stops the interval flushes. Anything still waiting is left for
+// flush.
+func (b *headBatch) close() {
+	select {
+	case <-b.done:
+	default:
+		close(b.stop)
+		<-b.done
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeadBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		interval time.Duration
+		links    []string
+		wait     time.Duration
+		want     []string
+		// How many batches went out before the crawl's final flush.
+		early int
+	}{
+		{
+			name:  "full batches go straight out",
+			size:  2,
+			links: []string{"a", "b", "a", "c", "d", "e"},
+			want:  []string{"[a b]", "[c d]", "[e]"},
+			early: 2,
+		},
+		{
+			name:  "no size",
+			links: []string{"a", "b", "c"},
+			want:  []string{"[a b c]"},
+		},
+		{
+			name:     "when the interval is up",
+			size:     10,
+			interval: 20 * time.Millisecond,
+			links:    []string{"a", "b"},
+			wait:     100 * time.Millisecond,
+			want:     []string{"[a b]"},
+			early:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			sent := []string{}
+			b := newHeadBatch(tt.size, tt.interval, func(links []string) {
+				mu.Lock()
+				sent = append(sent, fmt.Sprint(links))
+				mu.Unlock()
+			})
+			for _, link := range tt.links {
+				b.add(link)
+			}
+			time.Sleep(tt.wait)
+			b.close()
+			mu.Lock()
+			early := len(sent)
+			mu.Unlock()
+			if early != tt.early {
+				t.Errorf("got %d batches before the flush, want %d", early, tt.early)
+			}
+			for b.flush() {
+			}
+
+			if strings.Join(sent, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got batches %v, want %v", sent, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// bearerTransport sends -bearer-token to the hosts we're crawling, and to
+// nowhere else. If a request comes back 401 and there's a -token-refresh-cmd
+// we run it for a new token and try the request once more.
+type bearerTransport struct {
+	sync.Mutex
+	transport  http.RoundTripper
+	token      string
+	refreshCmd []string
+	inScope    map[string]bool
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.inScope[req.URL.Host] {
+		return t.transport.RoundTrip(req)
+	}
+
+	t.Lock()
+	token := t.token
+	t.Unlock()
+	resp, err := t.transport.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || len(t.refreshCmd) == 0 {
+		return resp, err
+	}
+	// We can't send a body twice unless we can get a fresh copy of it.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, err
+	}
+
+	token, refreshErr := t.refresh(token)
+	if refreshErr != nil {
+		return resp, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := withBearer(req, token)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.transport.RoundTrip(retry)
+}
+
+// refresh returns a new token in place of stale. When several requests are
+// turned away at once, only the first runs the command, and the rest use
+// the token it got.
+func (t *bearerTransport) refresh(stale string) (string, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.token != stale {
+		return t.token, nil
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(t.refreshCmd[0], t.refreshCmd[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("cannot refresh the bearer token: %v", err)
+	}
+	line, _ := bufio.NewReader(&stdout).ReadString('\n')
+	if line = strings.TrimSpace(line); line == "" {
+		return "", fmt.Errorf("%s printed no token", t.refreshCmd[0])
+	}
+	t.token = line
+	return t.token, nil
+}
+
+// withBearer returns a copy of req with the token in its Authorization
+// header, since a RoundTripper mustn't change the request it's given.
+func withBearer(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	if token != "" {
+		clone.Header.Set("Authorization", "Bearer "+token)
+	}
+	return clone
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tokenSite answers 200 to requests with its token, and 401 to the rest.
+// It keeps the Authorization header of each request it gets.
+type tokenSite struct {
+	sync.Mutex
+	token string
+	auth  []string
+}
+
+func (s *tokenSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := req.Header.Get("Authorization")
+	s.Lock()
+	s.auth = append(s.auth, auth)
+	s.Unlock()
+
+	status := http.StatusUnauthorized
+	if auth == "Bearer "+s.token {
+		status = http.StatusOK
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestBearerTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       string
+		token      string
+		refreshCmd []string
+		status     int
+		auth       []string
+		newToken   string
+	}{
+		{
+			name:   "in scope",
+			link:   "http://example.com/",
+			token:  "fresh",
+			status: 200,
+			auth:   []string{"Bearer fresh"},
+		},
+		{
+			name:   "out of scope",
+			link:   "http://other.test/",
+			token:  "fresh",
+			status: 401,
+			auth:   []string{""},
+		},
+		{
+			name:   "stale without a refresh command",
+			link:   "http://example.com/",
+			token:  "stale",
+			status: 401,
+			auth:   []string{"Bearer stale"},
+		},
+		{
+			name:       "refreshed",
+			link:       "http://example.com/",
+			token:      "stale",
+			refreshCmd: []string{"sh", "-c", "echo ' fresh '; echo other"},
+			status:     200,
+			auth:       []string{"Bearer stale", "Bearer fresh"},
+			newToken:   "fresh",
+		},
+		{
+			name:       "the refresh fails",
+			link:       "http://example.com/",
+			token:      "stale",
+			refreshCmd: []string{"sh", "-c", "exit 1"},
+			status:     401,
+			auth:       []string{"Bearer stale"},
+			newToken:   "stale",
+		},
+		{
+			name:       "the refresh prints nothing",
+			link:       "http://example.com/",
+			token:      "stale",
+			refreshCmd: []string{"true"},
+			status:     401,
+			auth:       []string{"Bearer stale"},
+			newToken:   "stale",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &tokenSite{token: "fresh"}
+			transport := &bearerTransport{
+				transport:  site,
+				token:      tt.token,
+				refreshCmd: tt.refreshCmd,
+				inScope:    map[string]bool{"example.com": true},
+			}
+			req, _ := http.NewRequest("GET", tt.link, nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if strings.Join(site.auth, ",") != strings.Join(tt.auth, ",") {
+				t.Errorf("got Authorization headers %q, want %q", site.auth, tt.auth)
+			}
+			if req.Header.Get("Authorization") != "" {
+				t.Error("the request we were given was changed")
+			}
+			if tt.newToken != "" && transport.token != tt.newToken {
+				t.Errorf("got token %q, want %q", transport.token, tt.newToken)
+			}
+		})
+	}
+}
+
+func TestBearerRefreshOnce(t *testing.T) {
+	site := &tokenSite{token: "fresh"}
+	transport := &bearerTransport{
+		transport:  site,
+		token:      "stale",
+		refreshCmd: []string{"sh", "-c", "echo fresh; echo run >> runs"},
+		inScope:    map[string]bool{"example.com": true},
+	}
+	t.Chdir(t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			if resp, err := transport.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	runs, err := os.ReadFile("runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(runs), "run"); n != 1 {
+		t.Errorf("the refresh command ran %d times, want once", n)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// circuitBreaker stops the crawl once too many requests have failed, for
+// -max-errors and -max-consecutive-errors, e.g. because the site went down
+// halfway through. A failure is a request with no response or a 5xx. Once
+// it has tripped we don't make any more requests, and report on what we
+// have.
+type circuitBreaker struct {
+	sync.Mutex
+	maxTotal       int
+	maxConsecutive int
+	total          int
+	consecutive    int
+	reason         string
+}
+
+// record counts a request, and returns why the breaker tripped if this is
+// the request which tripped it.
+func (b *circuitBreaker) record(failed bool) string {
+	b.Lock()
+	defer b.Unlock()
+	if !failed {
+		b.consecutive = 0
+		return ""
+	}
+	b.total++
+	b.consecutive++
+	if b.reason != "" {
+		return ""
+	}
+
+	switch {
+	case b.maxTotal > 0 && b.total > b.maxTotal:
+		b.reason = fmt.Sprintf("%d requests failed, more than -max-errors=%d", b.total, b.maxTotal)
+	case b.maxConsecutive > 0 && b.consecutive > b.maxConsecutive:
+		b.reason = fmt.Sprintf("%d requests in a row failed, more than -max-consecutive-errors=%d", b.consecutive, b.maxConsecutive)
+	}
+	return b.reason
+}
+
+// tripped returns why the breaker tripped, or "" if it hasn't.
+func (b *circuitBreaker) tripped() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.reason
+}
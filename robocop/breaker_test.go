@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxTotal       int
+		maxConsecutive int
+		// F for a failed request, . for one which worked.
+		requests string
+		trips    int
+		want     string
+	}{
+		{name: "no limits", requests: "FFFFFFFF"},
+		{name: "under -max-errors", maxTotal: 3, requests: "F.F.F."},
+		{name: "over -max-errors", maxTotal: 3, requests: "F.F.F.F.F", trips: 6, want: "4 requests failed, more than -max-errors=3"},
+		{name: "not in a row", maxConsecutive: 2, requests: "FF.FF.FF."},
+		{name: "in a row", maxConsecutive: 2, requests: "FF.FFFF", trips: 5, want: "3 requests in a row failed, more than -max-consecutive-errors=2"},
+		{name: "whichever comes first", maxTotal: 5, maxConsecutive: 2, requests: "F.FFF", trips: 4, want: "3 requests in a row failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &circuitBreaker{maxTotal: tt.maxTotal, maxConsecutive: tt.maxConsecutive}
+			trips := 0
+			for i, r := range tt.requests {
+				if reason := b.record(r == 'F'); reason != "" {
+					if trips != 0 {
+						t.Errorf("request %d tripped it again", i)
+					}
+					trips = i
+				}
+			}
+			if trips != tt.trips {
+				t.Errorf("request %d tripped it, want %d", trips, tt.trips)
+			}
+			got := b.tripped()
+			if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
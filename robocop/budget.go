@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+var errRequestBudget = errors.New("request budget exhausted")
+
+// requestBudget caps the number of HTTP requests we make over the whole
+// run, for -request-budget, and keeps track of where they went.
+type requestBudget struct {
+	sync.Mutex
+	max   int
+	spent int
+	hosts map[string]int
+}
+
+func newRequestBudget(max int) *requestBudget {
+	return &requestBudget{max: max, hosts: map[string]int{}}
+}
+
+// take spends a request on host, if there's any budget left.
+func (b *requestBudget) take(host string) bool {
+	b.Lock()
+	defer b.Unlock()
+	if b.spent >= b.max {
+		return false
+	}
+	b.spent++
+	b.hosts[host]++
+	return true
+}
+
+func (b *requestBudget) exhausted() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.spent >= b.max
+}
+
+// budgetTransport refuses to make requests once the budget has run out.
+// It sits underneath the retries and the HEAD fallback, so that every
+// request which actually goes out is counted. Responses from the cache
+// are free.
+type budgetTransport struct {
+	transport http.RoundTripper
+	budget    *requestBudget
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.budget.take(req.URL.Host) {
+		return nil, errRequestBudget
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// printBudget shows how the budget was spent, busiest host first.
+func printBudget(w io.Writer, b *requestBudget) {
+	b.Lock()
+	defer b.Unlock()
+
+	hosts := make([]string, 0, len(b.hosts))
+	for host := range b.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if b.hosts[hosts[i]] != b.hosts[hosts[j]] {
+			return b.hosts[hosts[i]] > b.hosts[hosts[j]]
+		}
+		return hosts[i] < hosts[j]
+	})
+
+	fmt.Fprintf(w, "requests:         %d of %d\n", b.spent, b.max)
+	for _, host := range hosts {
+		fmt.Fprintf(w, "  %-30s %d\n", host, b.hosts[host])
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRequestBudget(t *testing.T) {
+	// Thirty pages, each with links to three other sites, so there's
+	// plenty to spend the budget on.
+	home := ""
+	pages := map[string]stubPage{}
+	for i := 0; i < 30; i++ {
+		page := fmt.Sprintf("http://example.com/%d", i)
+		home += `<a href="` + page + `">page</a>`
+		body := ""
+		for _, host := range []string{"a.test", "b.test", "c.test"} {
+			link := fmt.Sprintf("http://%s/%d", host, i)
+			body += `<a href="` + link + `">link</a>`
+			pages[link] = stubPage{status: 200}
+		}
+		pages[page] = html(body)
+	}
+	pages["http://example.com/"] = html(home)
+
+	tests := []struct {
+		budget int
+		spent  int
+	}{
+		{budget: 1, spent: 1},
+		{budget: 2, spent: 2},
+		{budget: 10, spent: 10},
+		{budget: 50, spent: 50},
+		// One for the home page, the pages and the links on them.
+		{budget: 1000, spent: 1 + 30 + 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.budget), func(t *testing.T) {
+			site := newStubSite(pages)
+			opts := testOptions(t, fmt.Sprintf("-request-budget=%d", tt.budget))
+			res := newResults()
+			res.budget = newRequestBudget(opts.requestBudget)
+			testCrawlInto(t, res, site, "http://example.com/", opts)
+
+			requests := 0
+			for _, n := range site.requests {
+				requests += n
+			}
+			if requests > tt.budget {
+				t.Errorf("made %d requests, over the budget of %d", requests, tt.budget)
+			}
+			if requests != tt.spent || res.budget.spent != tt.spent {
+				t.Errorf("made %d requests and spent %d of the budget, want %d", requests, res.budget.spent, tt.spent)
+			}
+
+			perHost := 0
+			for _, n := range res.budget.hosts {
+				perHost += n
+			}
+			if perHost != res.budget.spent {
+				t.Errorf("the hosts add up to %d, but we spent %d", perHost, res.budget.spent)
+			}
+		})
+	}
+}
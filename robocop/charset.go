@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"mime"
+	"strings"
+
+	"github.com/gocolly/colly"
+	"golang.org/x/net/html/charset"
+)
+
+// decodeBody converts an HTML body to UTF-8 before we parse it. colly
+// already does this when the Content-Type header names a charset, but
+// plenty of pages only declare it in a <meta> tag, and parsing those as
+// UTF-8 garbles anchor text and can lose links.
+func decodeBody(r *colly.Response, verbose bool) {
+	contentType := r.Headers.Get("Content-Type")
+	if !strings.Contains(strings.ToLower(contentType), "html") {
+		return
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return
+	}
+
+	enc, name, _ := charset.DetermineEncoding(r.Body, contentType)
+	if name == "utf-8" {
+		return
+	}
+
+	body, err := enc.NewDecoder().Bytes(r.Body)
+	if err != nil {
+		if verbose {
+			log.Printf("cannot decode %v as %s because %v", r.Request.URL, name, err)
+		}
+		return
+	}
+	r.Body = body
+}
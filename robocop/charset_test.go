@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly"
+)
+
+func TestDecodeBody(t *testing.T) {
+	latin1 := "<html><head><meta charset=\"iso-8859-1\"></head><body><a href=\"/caf\xe9\">caf\xe9</a></body></html>"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "charset in a meta tag", contentType: "text/html", body: latin1, want: "café"},
+		{name: "already utf-8", contentType: "text/html", body: "<a>café</a>", want: "café"},
+		// colly has already decoded these.
+		{name: "charset in the header", contentType: "text/html; charset=iso-8859-1", body: latin1, want: "caf\xe9"},
+		{name: "not html", contentType: "text/plain", body: latin1, want: "caf\xe9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			headers.Set("Content-Type", tt.contentType)
+			r := &colly.Response{Body: []byte(tt.body), Headers: &headers}
+			decodeBody(r, false)
+			if !strings.Contains(string(r.Body), tt.want) {
+				t.Errorf("got body %q, want it to contain %q", r.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// Finding is a problem which a check found on a page. Status is what we
+// show in the report's status column, e.g. "mixed-content".
+type Finding struct {
+	Page   string
+	Link   string
+	Status string
+}
+
+// Check is run against every page we crawl.
+type Check interface {
+	Name() string
+	Run(page *colly.Response, doc *goquery.Document) []Finding
+}
+
+// A finisher is a Check which can only report once it has seen every page,
+// e.g. because it compares pages with each other.
+type finisher interface {
+	Finish() []Finding
+}
+
+// brokenCheck is the link status report itself. It isn't a Check because it
+// needs the status of every link, which we only have once the crawl is
+// over, so finishReport takes care of it.
+const brokenCheck = "broken"
+
+// registry makes the checks we know about, by name. Some checks keep
+// track of the pages they've seen, so each crawl gets checks of its own
+// rather than sharing them.
+var registry = map[string]func() Check{}
+
+func registerCheck(newCheck func() Check) {
+	registry[newCheck().Name()] = newCheck
+}
+
+func init() {
+	registerCheck(func() Check { return &ampCheck{amphtml: map[string]string{}, canonical: map[string]string{}} })
+	registerCheck(func() Check { return ampRequiredCheck{} })
+	registerCheck(func() Check { return anchorTextCheck{} })
+	registerCheck(func() Check { return &descriptionCheck{pages: map[string][]string{}} })
+	registerCheck(func() Check { return insecureFormCheck{} })
+	registerCheck(func() Check { return mailtoCheck{} })
+	registerCheck(func() Check { return mixedContentCheck{} })
+	registerCheck(func() Check { return selfLinkCheck{} })
+}
+
+// checkNames returns the names -checks accepts.
+func checkNames() []string {
+	names := []string{brokenCheck}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkList implements flag.Value for -checks.
+type checkList map[string]bool
+
+func (l checkList) String() string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (l checkList) Set(value string) error {
+	for name := range l {
+		delete(l, name)
+	}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := registry[name]; !ok && name != brokenCheck {
+			return fmt.Errorf("unknown check %q, choose from %s", name, strings.Join(checkNames(), ","))
+		}
+		l[name] = true
+	}
+	return nil
+}
+
+// enabledChecks returns the crawl's instance of each enabled check,
+// making them the first time they're needed.
+func (res *results) enabledChecks(opts *options) map[string]Check {
+	res.Lock()
+	defer res.Unlock()
+	if res.checks == nil {
+		res.checks = map[string]Check{}
+		for name := range opts.checks {
+			if newCheck, ok := registry[name]; ok {
+				res.checks[name] = newCheck()
+			}
+		}
+	}
+	return res.checks
+}
+
+// runChecks runs the enabled checks against a page and records what they
+// find.
+func runChecks(page *colly.Response, doc *goquery.Document, res *results, opts *options) {
+	for _, check := range res.enabledChecks(opts) {
+		findings := check.Run(page, doc)
+
+		res.Lock()
+		res.findings = append(res.findings, findings...)
+		res.Unlock()
+	}
+}
+
+// finishChecks records what the enabled finishers found, once the crawl is
+// over.
+func finishChecks(res *results, opts *options) {
+	for _, check := range res.enabledChecks(opts) {
+		f, ok := check.(finisher)
+		if !ok {
+			continue
+		}
+		findings := f.Finish()
+
+		res.Lock()
+		res.findings = append(res.findings, findings...)
+		res.Unlock()
+	}
+}
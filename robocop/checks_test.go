@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+func TestCheckListSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "broken", want: "broken"},
+		{value: "mixed, broken", want: "broken,mixed"},
+		{value: "mixed,,", want: "mixed"},
+		{value: "nonsense", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			// Set starts again from nothing, rather than adding to the
+			// default.
+			l := checkList{brokenCheck: true, "self": true}
+			err := l.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if err == nil && l.String() != tt.want {
+				t.Errorf("got checks %v, want %v", l.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNames(t *testing.T) {
+	names := checkNames()
+	for _, name := range []string{brokenCheck, "amp", "mixed", "self"} {
+		if !strings.Contains(strings.Join(names, ","), name) {
+			t.Errorf("%v isn't one of the checks: %v", name, names)
+		}
+	}
+}
+
+func TestMixedContentCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		body string
+		want []string
+	}{
+		{
+			name: "http resources on an https page",
+			page: "https://example.com/",
+			body: `<img src="http://example.com/a.png">
+				<script src="http://cdn.test/app.js"></script>
+				<link rel="stylesheet" href="http://cdn.test/app.css">`,
+			want: []string{"http://example.com/a.png", "http://cdn.test/app.js", "http://cdn.test/app.css"},
+		},
+		{
+			name: "https and relative resources",
+			page: "https://example.com/",
+			body: `<img src="/a.png"><script src="https://cdn.test/app.js"></script><img src="//cdn.test/b.png">`,
+		},
+		{
+			name: "links aren't resources",
+			page: "https://example.com/",
+			body: `<a href="http://example.com/">home</a><link rel="canonical" href="http://example.com/">`,
+		},
+		{
+			name: "an http page",
+			page: "http://example.com/",
+			body: `<img src="http://example.com/a.png">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, doc := testPage(t, tt.page, tt.body)
+			got := []string{}
+			for _, f := range (mixedContentCheck{}).Run(page, doc) {
+				if f.Status != "mixed-content" || f.Page != tt.page {
+					t.Errorf("got finding %+v", f)
+				}
+				got = append(got, f.Link)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// titleCheck flags pages without a <title>, and counts the pages it has
+// seen, so that it can say how many there were once the crawl is over.
+type titleCheck struct {
+	sync.Mutex
+	seen int
+}
+
+func (*titleCheck) Name() string {
+	return "title"
+}
+
+func (c *titleCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	c.Lock()
+	c.seen++
+	c.Unlock()
+	if doc.Find("title").Length() > 0 {
+		return nil
+	}
+	return []Finding{{Page: page.Request.URL.String(), Link: page.Request.URL.String(), Status: "missing-title"}}
+}
+
+func (c *titleCheck) Finish() []Finding {
+	c.Lock()
+	defer c.Unlock()
+	return []Finding{{Page: "http://example.com/", Link: "http://example.com/", Status: fmt.Sprintf("%d-pages", c.seen)}}
+}
+
+func TestRegisterCheck(t *testing.T) {
+	registerCheck(func() Check { return &titleCheck{} })
+	t.Cleanup(func() { delete(registry, "title") })
+
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": {
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><head><title>Home</title></head><body><a href="/untitled">Untitled</a></body></html>`,
+		},
+		"http://example.com/untitled": html(""),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "not enabled", want: []string{}},
+		{
+			name: "enabled",
+			args: []string{"-checks=broken,title"},
+			want: []string{"http://example.com/ 2-pages", "http://example.com/untitled missing-title"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, tt.args...)
+			// Each crawl starts again from nothing, so the second one
+			// doesn't count the first one's pages.
+			for i := 0; i < 2; i++ {
+				_, rows := testCrawl(t, site, "http://example.com/", opts)
+				if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+					t.Errorf("crawl %d: got report\n%s\nwant\n%s", i+1, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+				}
+			}
+		})
+	}
+}
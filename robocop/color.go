@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// useColor decides whether to colorize output for -color. "auto" only
+// colors terminals, so that piped output doesn't fill up with escape codes.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		info, err := f.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("-color must be one of auto, always or never, not %q", mode)
+}
+
+// colorStatus wraps a status column value in the color for its class.
+// Anything which isn't a status code is something like dns-error, so it's
+// a failure too.
+func colorStatus(status string) string {
+	if status == "" {
+		return status
+	}
+
+	color := ansiRed
+	if code, err := strconv.Atoi(status); err == nil {
+		switch {
+		case code < 300:
+			color = ansiGreen
+		case code < 400:
+			color = ansiYellow
+		}
+	}
+	return color + status + ansiReset
+}
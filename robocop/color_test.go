@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{status: "200", want: ansiGreen + "200" + ansiReset},
+		{status: "301", want: ansiYellow + "301" + ansiReset},
+		{status: "404", want: ansiRed + "404" + ansiReset},
+		{status: "dns-error", want: ansiRed + "dns-error" + ansiReset},
+		{status: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := colorStatus(tt.status); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	// A file is never a terminal.
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		mode    string
+		want    bool
+		wantErr bool
+	}{
+		{mode: "always", want: true},
+		{mode: "never"},
+		{mode: "auto"},
+		{mode: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := useColor(tt.mode, f)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
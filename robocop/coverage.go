@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+type pageCoverage struct {
+	page      string
+	links     int
+	checked   int
+	skipped   int
+	unchecked int
+}
+
+// coverage counts, for each page we crawled, the links we found on it,
+// how many of those we checked, how many we skipped because of their
+// scheme or -assume-ok-hosts, and how many we never got to, e.g. because
+// of -max-visits or -request-budget. Pages with the most unchecked links
+// come first.
+func coverage(res *results, opts *options) []pageCoverage {
+	pages := []pageCoverage{}
+	for page, links := range res.pages {
+		pc := pageCoverage{page: page, links: len(links)}
+		for link := range links {
+			checked := checkedURL(link, opts)
+			switch {
+			case res.heads[checked] != 0 || res.errors[checked] != "":
+				pc.checked++
+			case res.skipped[link] || res.assumedOK[link]:
+				pc.skipped++
+			default:
+				pc.unchecked++
+			}
+		}
+		pages = append(pages, pc)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].unchecked != pages[j].unchecked {
+			return pages[i].unchecked > pages[j].unchecked
+		}
+		return pages[i].page < pages[j].page
+	})
+	return pages
+}
+
+// printCoverage prints the coverage of each page, for -coverage, so that
+// a truncated or heavily filtered crawl doesn't look cleaner than it is.
+func printCoverage(w io.Writer, pages []pageCoverage) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Source Page", "Links", "Checked", "Skipped", "Unchecked"})
+	for _, pc := range pages {
+		table.Append([]string{
+			pc.page,
+			strconv.Itoa(pc.links),
+			strconv.Itoa(pc.checked),
+			strconv.Itoa(pc.skipped),
+			strconv.Itoa(pc.unchecked),
+		})
+	}
+	table.Render()
+}
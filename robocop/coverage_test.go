@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	res := newResults()
+	for _, link := range []string{
+		"http://example.com/a",
+		"http://example.com/b",
+		"http://nowhere.invalid/",
+		"mailto:me@example.com",
+		"http://twitter.com/me",
+		"http://example.com/never",
+	} {
+		res.addLink("http://example.com/", link, "a")
+	}
+	res.addLink("http://example.com/a", "http://example.com/", "a")
+	res.addLink("http://example.com/a", "http://example.com/later", "a")
+	res.addLink("http://example.com/a", "http://example.com/later#top", "a")
+	res.heads["http://example.com/"] = 200
+	res.heads["http://example.com/a"] = 200
+	res.heads["http://example.com/b"] = 404
+	res.heads["http://example.com/later"] = 200
+	res.errors["http://nowhere.invalid/"] = "dns-error"
+	res.skipped["mailto:me@example.com"] = true
+	res.assumedOK["http://twitter.com/me"] = true
+
+	tests := []struct {
+		name string
+		opts options
+		want []pageCoverage
+	}{
+		{
+			name: "fragments are links of their own",
+			want: []pageCoverage{
+				{page: "http://example.com/", links: 6, checked: 3, skipped: 2, unchecked: 1},
+				{page: "http://example.com/a", links: 3, checked: 2, unchecked: 1},
+			},
+		},
+		{
+			name: "unless we ignore them",
+			opts: options{ignoreFragments: true},
+			want: []pageCoverage{
+				{page: "http://example.com/", links: 6, checked: 3, skipped: 2, unchecked: 1},
+				{page: "http://example.com/a", links: 3, checked: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coverage(res, &tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
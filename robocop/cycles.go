@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// linkCycles finds the groups of pages we crawled which all link to each
+// other, for -detect-cycles: the strongly connected components of the link
+// graph with more than one page in them. Each group is sorted, and the
+// groups are sorted by their first page.
+func linkCycles(res *results, opts *options) [][]string {
+	graph := map[string][]string{}
+	for page, links := range res.pages {
+		for link := range links {
+			target := res.finalURL(link, checkedURL(link, opts))
+			if u, err := url.Parse(target); err == nil {
+				u.Fragment = ""
+				target = u.String()
+			}
+			if _, crawled := res.pages[target]; crawled && target != page {
+				graph[page] = append(graph[page], target)
+			}
+		}
+	}
+
+	pages := make([]string, 0, len(res.pages))
+	for page := range res.pages {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	// Tarjan's algorithm.
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var connect func(page string)
+	connect = func(page string) {
+		index[page] = len(index)
+		lowlink[page] = index[page]
+		stack = append(stack, page)
+		onStack[page] = true
+
+		for _, target := range graph[page] {
+			if _, seen := index[target]; !seen {
+				connect(target)
+				if lowlink[target] < lowlink[page] {
+					lowlink[page] = lowlink[target]
+				}
+			} else if onStack[target] && index[target] < lowlink[page] {
+				lowlink[page] = index[target]
+			}
+		}
+
+		if lowlink[page] != index[page] {
+			return
+		}
+		component := []string{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == page {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, page := range pages {
+		if _, seen := index[page]; !seen {
+			connect(page)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// printCycles prints a table of the pages in each cycle, numbered from 1.
+func printCycles(w io.Writer, cycles [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Cycle", "Page"})
+	for i, cycle := range cycles {
+		for _, page := range cycle {
+			table.Append([]string{strconv.Itoa(i + 1), page})
+		}
+	}
+	table.Render()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLinkCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages map[string]stubPage
+		want  string
+	}{
+		{
+			name: "a tree",
+			pages: map[string]stubPage{
+				"http://example.com/":  html(`<a href="/a">A</a><a href="/b">B</a><a href="#top">Top</a>`),
+				"http://example.com/a": html(`<a href="/b">B</a><a href="/a">Self</a>`),
+				"http://example.com/b": html(``),
+			},
+			want: "[]",
+		},
+		{
+			name: "two cycles",
+			pages: map[string]stubPage{
+				"http://example.com/":  html(`<a href="/a">A</a><a href="/x">X</a>`),
+				"http://example.com/a": html(`<a href="/b">B</a>`),
+				"http://example.com/b": html(`<a href="/c#more">C</a>`),
+				"http://example.com/c": html(`<a href="/a">A</a>`),
+				"http://example.com/x": html(`<a href="/y">Y</a>`),
+				"http://example.com/y": html(`<a href="/x">X</a>`),
+			},
+			want: "[[http://example.com/a http://example.com/b http://example.com/c] [http://example.com/x http://example.com/y]]",
+		},
+		{
+			name: "through a redirect",
+			pages: map[string]stubPage{
+				"http://example.com/":    html(`<a href="/a">A</a>`),
+				"http://example.com/a":   html(`<a href="/old">Old</a>`),
+				"http://example.com/old": {status: 301, location: "http://example.com/"},
+			},
+			want: "[[http://example.com/ http://example.com/a]]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, "-detect-cycles")
+			res, _ := testCrawl(t, newStubSite(tt.pages), "http://example.com/", opts)
+			cycles := linkCycles(res, opts)
+			if got := fmt.Sprint(cycles); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			var table bytes.Buffer
+			printCycles(&table, cycles)
+			for i, cycle := range cycles {
+				for _, page := range cycle {
+					if !strings.Contains(table.String(), fmt.Sprintf("%d | %s", i+1, page)) {
+						t.Errorf("%s isn't in cycle %d:\n%s", page, i+1, table.String())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// descriptionCheck flags pages with no meta description, and groups of
+// pages which share one. Search engines show the description under the
+// title, so duplicates make pages hard to tell apart.
+//
+// Duplicates can only be found once every page has been seen, so Run just
+// collects the descriptions and Finish reports on them.
+type descriptionCheck struct {
+	sync.Mutex
+	pages map[string][]string
+}
+
+func (*descriptionCheck) Name() string {
+	return "descriptions"
+}
+
+func (d *descriptionCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	source := page.Request.URL.String()
+
+	content, _ := doc.Find(`meta[name="description" i]`).First().Attr("content")
+	description := strings.Join(strings.Fields(content), " ")
+	if description == "" {
+		return []Finding{{
+			Page:   source,
+			Link:   source,
+			Status: "missing-description",
+		}}
+	}
+
+	d.Lock()
+	d.pages[description] = append(d.pages[description], source)
+	d.Unlock()
+	return nil
+}
+
+// Finish returns a finding for each description used by more than one
+// page. The pages go in the page column, one per line, like with
+// -list-referrers, and the description goes in the link column.
+func (d *descriptionCheck) Finish() []Finding {
+	d.Lock()
+	defer d.Unlock()
+
+	findings := []Finding{}
+	for description, pages := range d.pages {
+		if len(pages) < 2 {
+			continue
+		}
+		sort.Strings(pages)
+		findings = append(findings, Finding{
+			Page:   strings.Join(pages, "\n"),
+			Link:   description,
+			Status: "duplicate-description",
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Page < findings[j].Page
+	})
+	return findings
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDescriptionCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages map[string]string
+		want  []string
+	}{
+		{
+			name: "all different",
+			pages: map[string]string{
+				"https://example.com/a": `<meta name="description" content="About a">`,
+				"https://example.com/b": `<meta name="description" content="About b">`,
+			},
+			want: []string{},
+		},
+		{
+			name: "missing",
+			pages: map[string]string{
+				"https://example.com/a": `<meta name="keywords" content="a">`,
+				"https://example.com/b": `<meta name="description" content="  ">`,
+			},
+			want: []string{
+				"https://example.com/a https://example.com/a missing-description",
+				"https://example.com/b https://example.com/b missing-description",
+			},
+		},
+		{
+			name: "shared, up to case and whitespace in the markup",
+			pages: map[string]string{
+				"https://example.com/a": `<meta name="description" content="All about it">`,
+				"https://example.com/b": `<meta name="Description" content=" All  about
+					it ">`,
+				"https://example.com/c": `<meta name="description" content="Something else">`,
+			},
+			want: []string{
+				"https://example.com/a\nhttps://example.com/b All about it duplicate-description",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &descriptionCheck{pages: map[string][]string{}}
+			got := []string{}
+			for pageURL, head := range tt.pages {
+				page, doc := testPage(t, pageURL, "<html><head>"+head+"</head><body></body></html>")
+				for _, f := range check.Run(page, doc) {
+					got = append(got, fmt.Sprintf("%s %s %s", f.Page, f.Link, f.Status))
+				}
+			}
+			for _, f := range check.Finish() {
+				got = append(got, fmt.Sprintf("%s %s %s", f.Page, f.Link, f.Status))
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got findings\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckDescriptionsTwice(t *testing.T) {
+	page := func(description string) stubPage {
+		return stubPage{
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><head><meta name="description" content="` + description + `"></head><body><a href="/about">About</a></body></html>`,
+		}
+	}
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":      page("Home"),
+		"http://example.com/about": page("About us"),
+	})
+
+	// The second crawl sees the same pages, which mustn't make them
+	// duplicates of themselves.
+	opts := testOptions(t, "-check-descriptions")
+	for i := 0; i < 2; i++ {
+		if _, rows := testCrawl(t, site, "http://example.com/", opts); len(rows) != 0 {
+			t.Errorf("crawl %d: got report %v, want nothing", i+1, reported(rows))
+		}
+	}
+}
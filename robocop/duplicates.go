@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+type duplicateLink struct {
+	page  string
+	link  string
+	count int
+}
+
+// duplicateLinks returns the links we found more than once on the same
+// page, e.g. a call to action repeated down the page, most repeated
+// first.
+func duplicateLinks(res *results) []duplicateLink {
+	res.Lock()
+	defer res.Unlock()
+
+	dups := []duplicateLink{}
+	for page, counts := range res.linkCounts {
+		for link, count := range counts {
+			if count > 1 {
+				dups = append(dups, duplicateLink{page: page, link: link, count: count})
+			}
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].count != dups[j].count {
+			return dups[i].count > dups[j].count
+		}
+		if dups[i].page != dups[j].page {
+			return dups[i].page < dups[j].page
+		}
+		return dups[i].link < dups[j].link
+	})
+	return dups
+}
+
+// printDuplicateLinks prints the links pages have more than once, for
+// -report-duplicate-links.
+func printDuplicateLinks(w io.Writer, dups []duplicateLink) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Source Page", "Link", "Count"})
+	for _, dup := range dups {
+		table.Append([]string{dup.page, dup.link, strconv.Itoa(dup.count)})
+	}
+	table.Render()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages map[string]stubPage
+		want  []string
+	}{
+		{
+			name: "each link once",
+			pages: map[string]stubPage{
+				"http://example.com/":  html(`<a href="/a">A</a><a href="/b">B</a>`),
+				"http://example.com/a": html(`<a href="/b">B</a>`),
+			},
+			want: []string{},
+		},
+		{
+			name: "most repeated first",
+			pages: map[string]stubPage{
+				"http://example.com/": html(`
+					<a href="/a">A</a>
+					<a href="/signup">Sign up</a>
+					<a href="http://example.com/signup">Sign up</a>
+					<a href="/signup">Sign up</a>
+					<a href="/b">B</a>
+					<a href="/b">B again</a>`),
+				"http://example.com/a": html(`<a href="/b">B</a><a href="/b">B</a>`),
+			},
+			want: []string{
+				"http://example.com/ http://example.com/signup 3",
+				"http://example.com/ http://example.com/b 2",
+				"http://example.com/a http://example.com/b 2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := testCrawl(t, newStubSite(tt.pages), "http://example.com/", testOptions(t, "-report-duplicate-links"))
+			dups := duplicateLinks(res)
+
+			got := []string{}
+			for _, dup := range dups {
+				got = append(got, fmt.Sprintf("%s %s %d", dup.page, dup.link, dup.count))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			var table bytes.Buffer
+			printDuplicateLinks(&table, dups)
+			// The header is four words, so only the rows have three.
+			printed := []string{}
+			for _, line := range strings.Split(table.String(), "\n") {
+				if cells := strings.FieldsFunc(line, func(r rune) bool { return r == '|' || r == ' ' }); len(cells) == 3 {
+					printed = append(printed, strings.Join(cells, " "))
+				}
+			}
+			if strings.Join(printed, "\n") != strings.Join(got, "\n") {
+				t.Errorf("got the table\n%s", table.String())
+			}
+		})
+	}
+}
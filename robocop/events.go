@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// event is a line of -verbose-json. Only the fields which make sense for
+// the kind of event are set.
+type event struct {
+	Event    string    `json:"event"`
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Page     string    `json:"page,omitempty"`
+	Element  string    `json:"element,omitempty"`
+	Status   int       `json:"status,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Duration float64   `json:"duration_seconds,omitempty"`
+}
+
+// eventLog writes what the crawler is doing as one JSON object per line,
+// for -verbose-json, so that other tools can follow along: request and
+// response for every request that goes out, link for every link we find
+// and failure for every link which doesn't work. A nil eventLog writes
+// nothing.
+type eventLog struct {
+	sync.Mutex
+	enc   *json.Encoder
+	close func() error
+}
+
+// newEventLog writes to path, or to standard error for "-", since the
+// report goes to standard output.
+func newEventLog(path string) (*eventLog, error) {
+	if path == "-" {
+		return &eventLog{enc: json.NewEncoder(os.Stderr), close: func() error { return nil }}, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLog{enc: json.NewEncoder(file), close: file.Close}, nil
+}
+
+func (l *eventLog) emit(e event) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+
+	l.Lock()
+	defer l.Unlock()
+	_ = l.enc.Encode(e)
+}
+
+func (l *eventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.close()
+}
+
+// eventTransport logs the request and response events for each request
+// which goes out.
+type eventTransport struct {
+	transport http.RoundTripper
+	events    *eventLog
+}
+
+func (t *eventTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.events.emit(event{Event: "request", Method: req.Method, URL: req.URL.String()})
+
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	e := event{
+		Event:    "response",
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Status = resp.StatusCode
+	}
+	t.events.emit(e)
+	return resp, err
+}
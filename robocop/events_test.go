@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestVerboseJSON(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":        html(`<a href="/gone">Gone</a><a href="http://other.test/logo.png">Logo</a>`),
+		"http://other.test/logo.png": {status: 200},
+	})
+
+	path := filepath.Join(t.TempDir(), "events.json")
+	events, err := newEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := newResults()
+	res.events = events
+	testCrawlInto(t, res, site, "http://example.com/", testOptions(t))
+	if err := events.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got := map[string][]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("%v: %s", err, scanner.Text())
+		}
+		if e.Time.IsZero() {
+			t.Errorf("no time in %s", scanner.Text())
+		}
+		var line string
+		switch e.Event {
+		case "link":
+			line = e.Page + " " + e.Element + " " + e.URL
+		case "response", "failure":
+			line = e.Method + " " + e.URL + " " + strings.TrimSpace(strings.Join([]string{strconv.Itoa(e.Status), e.Error}, " "))
+		default:
+			line = e.Method + " " + e.URL
+		}
+		got[e.Event] = append(got[e.Event], line)
+	}
+
+	tests := []struct {
+		event string
+		want  []string
+	}{
+		{event: "request", want: []string{"GET http://example.com/", "GET http://example.com/gone", "HEAD http://other.test/logo.png"}},
+		{event: "response", want: []string{"GET http://example.com/ 200", "GET http://example.com/gone 404", "HEAD http://other.test/logo.png 200"}},
+		{event: "link", want: []string{"http://example.com/ a http://example.com/gone", "http://example.com/ a http://other.test/logo.png"}},
+		{event: "failure", want: []string{"GET http://example.com/gone 404"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			sort.Strings(got[tt.event])
+			if strings.Join(got[tt.event], "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got[tt.event], "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestNilEventLog(t *testing.T) {
+	var events *eventLog
+	events.emit(event{Event: "request"})
+	if err := events.Close(); err != nil {
+		t.Error(err)
+	}
+}
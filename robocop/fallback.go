@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// getInstead re-requests a link with GET for servers which won't answer a
+// HEAD properly, e.g. with 405 Method Not Allowed or 501 Not Implemented.
+// This goes around the collector, which won't GET a URL it has already
+// seen, and would crawl the result if it did.
+func getInstead(client *http.Client, userAgent, link string) (int, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// We only want the status, so don't bother reading the body.
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// getFirstByte GETs just the first byte of a link, for -head-then-range,
+// and returns the status. Servers which support ranges send 206, and the
+// rest send 200 with the whole body, which we don't read.
+func getFirstByte(client *http.Client, userAgent, link string) (int, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// fetchBody GETs a link and returns the body, giving up on anything over
+// maxSize bytes. Like getInstead, it goes around the collector, for when
+// we need the content of something colly only HEADed, e.g. for -check-pdf.
+func fetchBody(client *http.Client, userAgent, link string, maxSize int) ([]byte, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET returned %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("it is over %d bytes", maxSize)
+	}
+	return data, nil
+}
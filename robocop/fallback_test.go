@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// headerTransport keeps the value of a header for each URL it's asked
+// for, and answers from transport.
+type headerTransport struct {
+	sync.Mutex
+	transport http.RoundTripper
+	header    string
+	values    map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	t.values[req.URL.String()] = req.Header.Get(t.header)
+	t.Unlock()
+	return t.transport.RoundTrip(req)
+}
+
+func TestHeadMethodFallback(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="http://other.test/no-head">No HEAD</a>
+			<a href="http://other.test/not-implemented">Not implemented</a>
+			<a href="http://other.test/gone">Gone</a>`),
+		"http://other.test/no-head":         {status: 200, headStatus: 405},
+		"http://other.test/not-implemented": {status: 200, headStatus: 501},
+		"http://other.test/gone":            {status: 404, headStatus: 405},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "GET instead",
+			want: []string{"http://other.test/gone 404"},
+		},
+		{
+			name: "only for 501",
+			args: []string{"-head-method-fallback=501"},
+			want: []string{"http://other.test/gone 405", "http://other.test/no-head 405"},
+		},
+		{
+			name: "never",
+			args: []string{"-head-method-fallback="},
+			want: []string{"http://other.test/gone 405", "http://other.test/no-head 405", "http://other.test/not-implemented 501"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			got := reported(rows)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestHeadThenRange(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="http://other.test/file.zip">Works</a>
+			<a href="http://other.test/no-ranges.zip">No ranges</a>
+			<a href="http://other.test/broken.zip">Broken</a>`),
+		"http://other.test/file.zip":      {status: 206, headStatus: 200, contentType: "application/zip"},
+		"http://other.test/no-ranges.zip": {status: 416, headStatus: 200, contentType: "application/zip"},
+		"http://other.test/broken.zip":    {status: 500, headStatus: 200, contentType: "application/zip"},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "HEAD only", want: []string{}},
+		{
+			name: "with a range GET",
+			args: []string{"-head-then-range"},
+			want: []string{"http://other.test/broken.zip range-failed", "http://other.test/no-ranges.zip range-failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.requests = map[string]int{}
+			ranges := &headerTransport{transport: site, header: "Range", values: map[string]string{}}
+			_, rows := testCrawl(t, ranges, "http://example.com/", testOptions(t, tt.args...))
+			got := reported(rows)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			gets := 0
+			if len(tt.args) > 0 {
+				gets = 1
+			}
+			for _, link := range []string{"http://other.test/file.zip", "http://other.test/no-ranges.zip", "http://other.test/broken.zip"} {
+				if n := site.count("GET", link); n != gets {
+					t.Errorf("got %d GETs of %v, want %d", n, link, gets)
+				}
+				if gets > 0 && ranges.values[link] != "bytes=0-0" {
+					t.Errorf("got Range %q for %v, want bytes=0-0", ranges.values[link], link)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchBody(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/small": {status: 200, body: "12345"},
+		"http://example.com/large": {status: 200, body: "1234567890"},
+	})
+	client := &http.Client{Transport: site}
+
+	tests := []struct {
+		link    string
+		want    string
+		wantErr string
+	}{
+		{link: "http://example.com/small", want: "12345"},
+		{link: "http://example.com/large", wantErr: "over 8 bytes"},
+		{link: "http://example.com/missing", wantErr: "returned 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			data, err := fetchBody(client, "robocop", tt.link, 8)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one saying %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(data) != tt.want {
+				t.Errorf("got %q and error %v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
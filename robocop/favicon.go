@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// faviconSelector finds the icons a page declares, for -check-favicon.
+const faviconSelector = `link[rel~="icon" i][href], link[rel~="apple-touch-icon" i][href], link[rel~="apple-touch-icon-precomposed" i][href]`
+
+// defaultFavicon is where browsers look for an icon when a page doesn't
+// declare one.
+const defaultFavicon = "/favicon.ico"
+
+// iconElement is what we report as the element for an icon link:
+// apple-touch-icon for the ones iOS uses, and icon for the rest.
+func iconElement(link *goquery.Selection) string {
+	for _, rel := range strings.Fields(strings.ToLower(link.AttrOr("rel", ""))) {
+		if strings.HasPrefix(rel, "apple-touch-icon") {
+			return "apple-touch-icon"
+		}
+	}
+	return "icon"
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIconElement(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want string
+	}{
+		{rel: "icon", want: "icon"},
+		{rel: "shortcut icon", want: "icon"},
+		{rel: "Apple-Touch-Icon", want: "apple-touch-icon"},
+		{rel: "apple-touch-icon-precomposed", want: "apple-touch-icon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			_, doc := testPage(t, "https://example.com/", `<link rel="`+tt.rel+`" href="/icon.png">`)
+			if got := iconElement(doc.Find("link")); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFavicon(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		args []string
+		want []string
+	}{
+		{name: "without", head: `<link rel="icon" href="/gone.png">`, want: []string{}},
+		{
+			name: "declared",
+			head: `<link rel="shortcut icon" href="/gone.png"><link rel="apple-touch-icon" href="/touch.png">`,
+			args: []string{"-check-favicon"},
+			want: []string{"http://example.com/gone.png 404 icon", "http://example.com/touch.png 404 apple-touch-icon"},
+		},
+		{
+			// iOS has its own icons, which don't stand in for a favicon.
+			name: "only for iOS",
+			head: `<link rel="apple-touch-icon" href="/touch.png">`,
+			args: []string{"-check-favicon"},
+			want: []string{"http://example.com/favicon.ico 404 favicon", "http://example.com/touch.png 404 apple-touch-icon"},
+		},
+		{
+			name: "not declared",
+			args: []string{"-check-favicon"},
+			want: []string{"http://example.com/favicon.ico 404 favicon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newStubSite(map[string]stubPage{
+				"http://example.com/": {
+					status:      200,
+					contentType: "text/html",
+					body:        "<html><head>" + tt.head + "</head><body></body></html>",
+				},
+			})
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[linkColumn]+" "+row[statusColumn]+" "+row[elementColumn])
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
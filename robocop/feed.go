@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// feedSelector finds the RSS and Atom feeds a page advertises.
+const feedSelector = `link[rel~="alternate" i][type="application/rss+xml" i][href], link[rel~="alternate" i][type="application/atom+xml" i][href]`
+
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+type feedItem struct {
+	Links []feedLink `xml:"link"`
+}
+
+// feedDocument covers RSS 2.0, where the items are in the channel, RSS
+// 1.0, where they're at the top level, and Atom, which has entries.
+type feedDocument struct {
+	Channel struct {
+		Items []feedItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []feedItem `xml:"item"`
+	Entries []feedItem `xml:"entry"`
+}
+
+// isFeed is true for responses which say they're RSS or Atom. With
+// anyXML, which is cheap once we have the body, any XML will do, since
+// lots of feeds are served as text/xml.
+func isFeed(contentType string, anyXML bool) bool {
+	contentType = strings.ToLower(contentType)
+	if anyXML {
+		return strings.Contains(contentType, "xml")
+	}
+	return strings.Contains(contentType, "rss+xml") || strings.Contains(contentType, "atom+xml")
+}
+
+// feedLinks returns the links of the items in an RSS or Atom feed. RSS
+// has the URL as the text of <link>. Atom puts it in href, and an entry
+// can have several, of which we want the alternate one.
+func feedLinks(data []byte) ([]string, error) {
+	var doc feedDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	links := []string{}
+	items := append(append(doc.Channel.Items, doc.Items...), doc.Entries...)
+	for _, item := range items {
+		for _, link := range item.Links {
+			if link.Href == "" {
+				if text := strings.TrimSpace(link.Text); text != "" {
+					links = append(links, text)
+				}
+				continue
+			}
+			if link.Rel == "" || link.Rel == "alternate" {
+				links = append(links, link.Href)
+			}
+		}
+	}
+	return links, nil
+}
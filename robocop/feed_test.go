@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsFeed(t *testing.T) {
+	tests := []struct {
+		contentType string
+		anyXML      bool
+		want        bool
+	}{
+		{contentType: "application/rss+xml", want: true},
+		{contentType: "Application/Atom+XML; charset=utf-8", want: true},
+		{contentType: "text/xml"},
+		{contentType: "text/xml", anyXML: true, want: true},
+		{contentType: "text/html", anyXML: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isFeed(tt.contentType, tt.anyXML); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		feed    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "RSS 2.0",
+			feed: `<rss version="2.0"><channel>
+				<link>https://example.com/</link>
+				<item><link> https://example.com/a </link></item>
+				<item><title>No link</title></item>
+				<item><link>https://example.com/b</link></item>
+			</channel></rss>`,
+			want: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name: "RSS 1.0",
+			feed: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+				<channel><link>https://example.com/</link></channel>
+				<item><link>https://example.com/a</link></item>
+			</rdf:RDF>`,
+			want: []string{"https://example.com/a"},
+		},
+		{
+			name: "Atom",
+			feed: `<feed xmlns="http://www.w3.org/2005/Atom">
+				<link rel="self" href="https://example.com/feed.xml"/>
+				<entry>
+					<link rel="edit" href="https://example.com/edit/a"/>
+					<link rel="alternate" href="https://example.com/a"/>
+				</entry>
+				<entry><link href="https://example.com/b"/></entry>
+			</feed>`,
+			want: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{name: "not XML", feed: `<rss><channel>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := feedLinks([]byte(tt.feed))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got links\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// suggestFix returns the URL a report row's link should be changed to,
+// where we know it: the https version of an http link when that works,
+// the target of a redirect which only adds or removes a trailing slash,
+// and the canonical host for -check-www. Anything else gets no
+// suggestion, since we can't tell where a broken link should go.
+func suggestFix(row []string, res *results) string {
+	link := row[linkColumn]
+
+	if row[statusColumn] == "www-inconsistency" {
+		u, err := url.Parse(link)
+		if err != nil {
+			return ""
+		}
+		res.Lock()
+		canonical, ok := res.canonicalHosts[u.Host]
+		res.Unlock()
+		if !ok {
+			return ""
+		}
+		u.Host = canonical
+		return u.String()
+	}
+
+	if code, err := strconv.Atoi(row[httpsStatusColumn]); err == nil && code >= 200 && code < 300 {
+		return row[httpsLinkColumn]
+	}
+
+	for _, target := range []string{row[locationColumn], row[finalURLColumn]} {
+		if target != "" && target != link && strings.TrimSuffix(target, "/") == strings.TrimSuffix(link, "/") {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestSuggestFix(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   string
+		status string
+		column int
+		value  string
+		want   string
+	}{
+		{name: "broken", link: "http://example.com/gone", status: "404"},
+		{
+			name: "https works", link: "http://example.com/a", status: "200",
+			column: httpsStatusColumn, value: "200", want: "https://example.com/a",
+		},
+		{
+			name: "https doesn't", link: "http://example.com/a", status: "200",
+			column: httpsStatusColumn, value: "404",
+		},
+		{
+			name: "added a slash", link: "http://example.com/docs", status: "301",
+			column: locationColumn, value: "http://example.com/docs/", want: "http://example.com/docs/",
+		},
+		{
+			name: "removed a slash", link: "http://example.com/docs/", status: "200",
+			column: finalURLColumn, value: "http://example.com/docs", want: "http://example.com/docs",
+		},
+		{
+			name: "moved", link: "http://example.com/docs", status: "301",
+			column: locationColumn, value: "http://example.com/manual/",
+		},
+		{
+			name: "www", link: "http://www.example.com/a?b=1", status: "www-inconsistency",
+			want: "http://example.com/a?b=1",
+		},
+		{name: "a host we know nothing about", link: "http://www.other.test/", status: "www-inconsistency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			res.canonicalHosts["www.example.com"] = "example.com"
+			row := reportRow("http://example.com/", tt.link, tt.status)
+			row[httpsLinkColumn] = "https" + tt.link[len("http"):]
+			if tt.value != "" {
+				row[tt.column] = tt.value
+			}
+			if got := suggestFix(row, res); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stringList implements flag.Value for flags which can be repeated or
+// given a comma separated list, e.g. -host=a.com -host=b.com,c.com
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// statusList implements flag.Value for comma separated lists of status
+// codes, e.g. -head-method-fallback=405,501
+type statusList map[int]bool
+
+func (l statusList) String() string {
+	codes := make([]int, 0, len(l))
+	for code := range l {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	s := make([]string, 0, len(codes))
+	for _, code := range codes {
+		s = append(s, strconv.Itoa(code))
+	}
+	return strings.Join(s, ",")
+}
+
+// Set replaces any defaults rather than adding to them.
+func (l statusList) Set(value string) error {
+	for code := range l {
+		delete(l, code)
+	}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%q is not a status code", v)
+		}
+		l[code] = true
+	}
+	return nil
+}
+
+// stringSet implements flag.Value for comma separated sets of values, e.g.
+// -schemes=http,https
+type stringSet map[string]bool
+
+func (s stringSet) String() string {
+	values := make([]string, 0, len(s))
+	for v := range s {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+// Set replaces any defaults rather than adding to them.
+func (s stringSet) Set(value string) error {
+	for v := range s {
+		delete(s, v)
+	}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			s[v] = true
+		}
+	}
+	return nil
+}
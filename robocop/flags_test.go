@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestStringList(t *testing.T) {
+	var l stringList
+	for _, value := range []string{"a.com", "b.com, c.com", ",,"} {
+		if err := l.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := l.String(); got != "a.com,b.com,c.com" {
+		t.Errorf("got %v, want every value in the order given", got)
+	}
+}
+
+func TestStatusList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "501,405", want: "405,501"},
+		{value: " 404 , 410,", want: "404,410"},
+		{value: "", want: ""},
+		{value: "404,gone", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			// Set replaces the defaults.
+			l := statusList{405: true, 501: true}
+			err := l.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if err == nil && l.String() != tt.want {
+				t.Errorf("got %v, want %v", l.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStringSet(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "https", want: "https"},
+		{value: "mailto, https,https", want: "https,mailto"},
+		{value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			s := stringSet{"http": true, "https": true}
+			if err := s.Set(tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.String(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
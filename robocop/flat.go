@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// printFlat writes one line per failure as STATUS<tab>SOURCE<tab>LINK,
+// sorted, so that reports are easy to grep and to diff between runs.
+func printFlat(w io.Writer, rows linkReport) error {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		// With -list-referrers the sources have been joined together.
+		for _, source := range strings.Split(row[sourceColumn], "\n") {
+			lines = append(lines, strings.Join([]string{row[statusColumn], source, row[linkColumn]}, "\t"))
+		}
+	}
+	sort.Strings(lines)
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintFlat(t *testing.T) {
+	tests := []struct {
+		name string
+		rows linkReport
+		want string
+	}{
+		{name: "nothing broken"},
+		{
+			name: "sorted by status, then source",
+			rows: linkReport{
+				reportRow("http://example.com/b", "http://example.com/gone", "404"),
+				reportRow("http://example.com/a", "http://example.com/gone", "404"),
+				reportRow("http://example.com/", "http://other.test/", "500"),
+				reportRow("http://example.com/", "http://example.com/old", "301"),
+			},
+			want: "301\thttp://example.com/\thttp://example.com/old\n" +
+				"404\thttp://example.com/a\thttp://example.com/gone\n" +
+				"404\thttp://example.com/b\thttp://example.com/gone\n" +
+				"500\thttp://example.com/\thttp://other.test/\n",
+		},
+		{
+			name: "one line for each referrer",
+			rows: linkReport{
+				reportRow("http://example.com/b\nhttp://example.com/a", "http://example.com/gone", "404"),
+			},
+			want: "404\thttp://example.com/a\thttp://example.com/gone\n" +
+				"404\thttp://example.com/b\thttp://example.com/gone\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := printFlat(&out, tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// formSelector finds forms which submit somewhere other than the page
+// they're on.
+const formSelector = "form[action]"
+
+// insecureFormCheck flags https pages with forms which submit over http,
+// where anyone on the way can read what was typed in.
+type insecureFormCheck struct{}
+
+func (insecureFormCheck) Name() string {
+	return "forms"
+}
+
+func (insecureFormCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	if page.Request.URL.Scheme != "https" {
+		return nil
+	}
+
+	// A <base href> changes where relative actions go, and could take them
+	// off https.
+	base := page.Request.URL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+
+	findings := []Finding{}
+	doc.Find(formSelector).Each(func(_ int, s *goquery.Selection) {
+		action, err := base.Parse(s.AttrOr("action", ""))
+		if err != nil || action.Scheme != "http" {
+			return
+		}
+		findings = append(findings, Finding{
+			Page:   page.Request.URL.String(),
+			Link:   action.String(),
+			Status: "insecure-form",
+		})
+	})
+	return findings
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsecureFormCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		body string
+		want string
+	}{
+		{name: "https", page: "https://example.com/", body: `<form action="https://example.com/login"></form>`},
+		{name: "relative", page: "https://example.com/", body: `<form action="/login"></form>`},
+		{name: "http", page: "https://example.com/", body: `<form action="http://example.com/login"></form>`, want: "http://example.com/login"},
+		{name: "on an http page", page: "http://example.com/", body: `<form action="http://example.com/login"></form>`},
+		{
+			name: "relative to an http base",
+			page: "https://example.com/",
+			body: `<base href="http://example.com/app/"><form action="login"></form>`,
+			want: "http://example.com/app/login",
+		},
+		{
+			name: "relative to an https base",
+			page: "https://example.com/",
+			body: `<base href="/app/"><form action="login"></form>`,
+		},
+		{
+			name: "more than one",
+			page: "https://example.com/",
+			body: `<form action="http://a.test/"></form><form action="search"></form><form action="http://b.test/"></form>`,
+			want: "http://a.test/ http://b.test/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, doc := testPage(t, tt.page, tt.body)
+			got := []string{}
+			for _, f := range (insecureFormCheck{}).Run(page, doc) {
+				if f.Status != "insecure-form" || f.Page != tt.page {
+					t.Errorf("got finding %v", f)
+				}
+				got = append(got, f.Link)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
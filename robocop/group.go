@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// severityRank orders the sections of -group-by-status, worst first.
+var severityRank = map[string]int{
+	severityError:   0,
+	severityWarning: 1,
+	severityOK:      2,
+}
+
+// printGroupedReport prints a table per status, e.g. all of the 404s and
+// then all of the 500s, with errors ahead of warnings. Each section keeps
+// the rows in the report's order.
+func printGroupedReport(w io.Writer, rows linkReport, color bool, maxURLWidth int) {
+	sections := map[string]linkReport{}
+	for _, row := range rows {
+		sections[row[statusColumn]] = append(sections[row[statusColumn]], row)
+	}
+
+	statuses := make([]string, 0, len(sections))
+	for status := range sections {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := severityRank[severity(statuses[i])], severityRank[severity(statuses[j])]
+		if a != b {
+			return a < b
+		}
+		return statuses[i] < statuses[j]
+	})
+
+	for _, status := range statuses {
+		section := sections[status]
+
+		heading := status
+		if color {
+			heading = colorStatus(status)
+		}
+		fmt.Fprintf(w, "\n%s (%d)\n", heading, len(section))
+		printReport(w, section, color, maxURLWidth)
+	}
+}
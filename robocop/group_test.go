@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintGroupedReport(t *testing.T) {
+	// In the report's order, which isn't by source page.
+	rows := linkReport{
+		reportRow("http://example.com/z", "http://example.com/a", "404"),
+		reportRow("http://example.com/a", "http://example.com/b", "404"),
+		reportRow("http://example.com/a", "http://example.com/old", "301"),
+		reportRow("http://example.com/m", "http://example.com/c", "404"),
+		reportRow("http://example.com/a", "http://nowhere.invalid/", "dns-error"),
+	}
+
+	var out bytes.Buffer
+	printGroupedReport(&out, rows, false, 0)
+
+	// Errors first, then warnings, and each section in the report's order.
+	want := []string{
+		"404 (3)",
+		"http://example.com/a",
+		"http://example.com/b",
+		"http://example.com/c",
+		"dns-error (1)",
+		"http://nowhere.invalid/",
+		"301 (1)",
+		"http://example.com/old",
+	}
+	got := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		cells := strings.Split(line, "|")
+		switch {
+		case strings.HasSuffix(line, ")"):
+			got = append(got, line)
+		case len(cells) > 2 && strings.HasPrefix(strings.TrimSpace(cells[1]), "http"):
+			got = append(got, strings.TrimSpace(cells[2]))
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got sections\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
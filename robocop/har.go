@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var errNotInHAR = errors.New("no response recorded in the HAR file")
+
+// harFile is the part of the HTTP Archive format we need to replay the
+// responses in it.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content struct {
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// harTransport answers requests from the responses recorded in a HAR
+// file, so that a captured session can be audited offline. Nothing goes
+// out over the network; a request for a URL which wasn't recorded fails
+// with errNotInHAR.
+type harTransport struct {
+	responses map[string]harResponse
+}
+
+func newHARTransport(path string) (*harTransport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+
+	t := &harTransport{responses: map[string]harResponse{}}
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		u.Fragment = ""
+
+		body := []byte(entry.Response.Content.Text)
+		if entry.Response.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(entry.Response.Content.Text); err != nil {
+				return nil, fmt.Errorf("cannot decode the body of %s: %v", u, err)
+			}
+		}
+
+		header := http.Header{}
+		for _, h := range entry.Response.Headers {
+			header.Add(h.Name, h.Value)
+		}
+		// The recorded body has already been decoded.
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+
+		// Browsers record the last response when a URL is fetched more
+		// than once, so the last one wins here too.
+		t.responses[harKey(entry.Request.Method, u.String())] = harResponse{
+			status: entry.Response.Status,
+			header: header,
+			body:   body,
+		}
+	}
+	return t, nil
+}
+
+func harKey(method, link string) string {
+	return strings.ToUpper(method) + " " + link
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, ok := t.responses[harKey(req.Method, req.URL.String())]
+	// Browsers don't send HEADs, so answer them from the GET.
+	if !ok && req.Method == "HEAD" {
+		recorded, ok = t.responses[harKey("GET", req.URL.String())]
+		recorded.body = nil
+	}
+	if !ok {
+		return nil, errNotInHAR
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.status, http.StatusText(recorded.status)),
+		StatusCode:    recorded.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recorded.body)),
+		ContentLength: int64(len(recorded.body)),
+		Request:       req,
+	}, nil
+}
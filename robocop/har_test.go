@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHAR = `{"log": {"entries": [
+	{
+		"request": {"method": "GET", "url": "http://example.com/#top"},
+		"response": {
+			"status": 200,
+			"headers": [
+				{"name": "Content-Type", "value": "text/html"},
+				{"name": "Content-Encoding", "value": "gzip"},
+				{"name": "Content-Length", "value": "12"}
+			],
+			"content": {"text": "<a href=\"/gone\">"}
+		}
+	},
+	{
+		"request": {"method": "GET", "url": "http://example.com/image.png"},
+		"response": {"status": 200, "content": {"text": "iVBORw==", "encoding": "base64"}}
+	},
+	{
+		"request": {"method": "GET", "url": "http://example.com/gone"},
+		"response": {"status": 200, "content": {"text": "first"}}
+	},
+	{
+		"request": {"method": "get", "url": "http://example.com/gone"},
+		"response": {"status": 404, "content": {"text": "last"}}
+	},
+	{
+		"request": {"method": "POST", "url": "http://example.com/form"},
+		"response": {"status": 303, "headers": [{"name": "Location", "value": "/"}]}
+	}
+]}}`
+
+func writeHAR(t *testing.T, har string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err := os.WriteFile(path, []byte(har), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHARTransport(t *testing.T) {
+	transport, err := newHARTransport(writeHAR(t, testHAR))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method  string
+		link    string
+		status  int
+		body    string
+		header  string
+		wantErr error
+	}{
+		{method: "GET", link: "http://example.com/", status: 200, body: `<a href="/gone">`, header: "Content-Type: text/html"},
+		{method: "HEAD", link: "http://example.com/", status: 200, header: "Content-Type: text/html"},
+		{method: "GET", link: "http://example.com/image.png", status: 200, body: "\x89PNG"},
+		{method: "GET", link: "http://example.com/gone", status: 404, body: "last"},
+		{method: "POST", link: "http://example.com/form", status: 303, header: "Location: /"},
+		{method: "HEAD", link: "http://example.com/form", wantErr: errNotInHAR},
+		{method: "GET", link: "http://other.test/", wantErr: errNotInHAR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.link, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.link, nil)
+			resp, err := transport.RoundTrip(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			var header strings.Builder
+			resp.Header.Write(&header)
+			if resp.StatusCode != tt.status || string(body) != tt.body || strings.TrimSpace(header.String()) != tt.header {
+				t.Errorf("got %d %q with headers %q, want %d %q with %q", resp.StatusCode, body, header.String(), tt.status, tt.body, tt.header)
+			}
+		})
+	}
+}
+
+func TestHARTransportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		har  string
+		want string
+	}{
+		{name: "not JSON", har: `{"log": `, want: "cannot parse"},
+		{
+			name: "bad base64",
+			har:  `{"log": {"entries": [{"request": {"method": "GET", "url": "http://example.com/"}, "response": {"content": {"text": "!", "encoding": "base64"}}}]}}`,
+			want: "cannot decode the body of http://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newHARTransport(writeHAR(t, tt.har))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
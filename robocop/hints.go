@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+)
+
+// resourceHints returns the preload and prefetch URLs in a Link header,
+// e.g. </app.js>; rel=preload; as=script, along with the rel each was
+// given. preconnect and dns-prefetch name an origin rather than a
+// resource, so there's nothing for us to check.
+func resourceHints(headers []string) map[string]string {
+	hints := map[string]string{}
+	for _, header := range headers {
+		for _, value := range splitLinkHeader(header) {
+			value = strings.TrimSpace(value)
+			end := strings.Index(value, ">")
+			if !strings.HasPrefix(value, "<") || end == -1 {
+				continue
+			}
+			target := strings.TrimSpace(value[1:end])
+
+			for _, param := range strings.Split(value[end+1:], ";") {
+				kv := strings.SplitN(param, "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(kv[1]), `"`))) {
+					if rel == "preload" || rel == "prefetch" {
+						hints[target] = rel
+					}
+				}
+			}
+		}
+	}
+	return hints
+}
+
+// splitLinkHeader splits a Link header into its links. Commas inside the
+// <> can be part of the URL, so we can't just split on them.
+func splitLinkHeader(header string) []string {
+	var values []string
+	inURL, inQuotes, start := false, false, 0
+	for i, c := range header {
+		switch {
+		case c == '<' && !inQuotes:
+			inURL = true
+		case c == '>' && !inQuotes:
+			inURL = false
+		case c == '"' && !inURL:
+			inQuotes = !inQuotes
+		case c == ',' && !inURL && !inQuotes:
+			values = append(values, header[start:i])
+			start = i + 1
+		}
+	}
+	return append(values, header[start:])
+}
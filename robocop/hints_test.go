@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResourceHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    map[string]string
+	}{
+		{name: "none", want: map[string]string{}},
+		{
+			name:    "preload and prefetch",
+			headers: []string{`</app.js>; rel=preload; as=script, </next.html>; rel="prefetch"`},
+			want:    map[string]string{"/app.js": "preload", "/next.html": "prefetch"},
+		},
+		{
+			name:    "origins aren't resources",
+			headers: []string{`<https://cdn.test>; rel=preconnect, <https://fonts.test>; rel=dns-prefetch`},
+			want:    map[string]string{},
+		},
+		{
+			name:    "one of several rels",
+			headers: []string{`</font.woff2>; REL="preconnect Preload"; crossorigin`},
+			want:    map[string]string{"/font.woff2": "preload"},
+		},
+		{
+			name:    "commas in the URL and the params",
+			headers: []string{`</a,b.css>; rel=preload; title="x, y", </c.css>; rel=preload`},
+			want:    map[string]string{"/a,b.css": "preload", "/c.css": "preload"},
+		},
+		{
+			name:    "more than one header",
+			headers: []string{`</a.js>; rel=preload`, `</b.js>; rel=preload`},
+			want:    map[string]string{"/a.js": "preload", "/b.js": "preload"},
+		},
+		{
+			name:    "not a link",
+			headers: []string{`/a.js; rel=preload, <broken; rel=preload`},
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resourceHints(tt.headers)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLinkHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: []string{""}},
+		{header: "</a>; rel=preload", want: []string{"</a>; rel=preload"}},
+		{header: "</a>, </b>", want: []string{"</a>", " </b>"}},
+		{header: `</a,b>; title="c, d", </e>`, want: []string{`</a,b>; title="c, d"`, " </e>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := splitLinkHeader(tt.header); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultIgnoreFile is read from the working directory, if it's there and
+// -ignore-file isn't given.
+const defaultIgnoreFile = ".linkauditorignore"
+
+// ignorePattern is a line of an ignore file, e.g.
+// https://example.com/old/*. A * matches anything, slashes included, and
+// a ? matches any one character. A pattern without a scheme, like
+// example.com/old/*, matches http and https alike.
+type ignorePattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// ignoreList is the patterns in an ignore file. The links they match are
+// neither checked nor reported, so that a team can commit the links they
+// know are broken and have decided to live with.
+type ignoreList []ignorePattern
+
+// readIgnoreFile reads the patterns in path, one per line. Blank lines and
+// lines starting with # are left out, as in .gitignore.
+func readIgnoreFile(path string) (ignoreList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list ignoreList
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		glob := regexp.QuoteMeta(line)
+		glob = strings.ReplaceAll(glob, `\*`, ".*")
+		glob = strings.ReplaceAll(glob, `\?`, ".")
+		list = append(list, ignorePattern{pattern: line, re: regexp.MustCompile("^" + glob + "$")})
+	}
+	return list, scanner.Err()
+}
+
+// ignores returns the pattern matching link, if there is one.
+func (l ignoreList) ignores(link string) (string, bool) {
+	bare := link
+	if i := strings.Index(link, "://"); i >= 0 {
+		bare = link[i+3:]
+	}
+	for _, p := range l {
+		target := link
+		if !strings.Contains(p.pattern, "://") {
+			target = bare
+		}
+		if p.re.MatchString(target) {
+			return p.pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ignoring is an ignore list with the given patterns.
+func ignoring(t *testing.T, patterns ...string) ignoreList {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), defaultIgnoreFile)
+	if err := os.WriteFile(path, []byte(strings.Join(patterns, "\n")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	list, err := readIgnoreFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestIgnores(t *testing.T) {
+	list := ignoring(t,
+		"# links we've decided to live with",
+		"",
+		"  https://example.com/old/*  ",
+		"example.com/legacy?.html",
+		"*.tracker.test/*",
+		"https://docs.example.com/v1.0/",
+	)
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "https://example.com/old/a", want: "https://example.com/old/*"},
+		{link: "https://example.com/old/a/b?c=d", want: "https://example.com/old/*"},
+		{link: "http://example.com/old/a"},
+		{link: "http://example.com/legacy1.html", want: "example.com/legacy?.html"},
+		{link: "https://example.com/legacy2.html", want: "example.com/legacy?.html"},
+		{link: "https://example.com/legacy10.html"},
+		{link: "https://pixel.tracker.test/1x1.gif", want: "*.tracker.test/*"},
+		{link: "https://docs.example.com/v1.0/", want: "https://docs.example.com/v1.0/"},
+		// The dot is a dot, not any character.
+		{link: "https://docs.example.com/v1x0/"},
+		{link: "https://example.com/new/a"},
+		{link: "mailto:me@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, ok := list.ignores(tt.link)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("got %q %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name: "nothing ignored",
+			want: []string{"http://example.com/gone 404", "http://example.com/old/gone 404", "http://other.test/gone 404"},
+		},
+		{
+			name:     "ignored",
+			patterns: []string{"example.com/old/*", "http://other.test/*"},
+			want:     []string{"http://example.com/gone 404"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newStubSite(map[string]stubPage{
+				"http://example.com/": html(`<a href="/old/gone">Old</a><a href="/gone">Gone</a><a href="http://other.test/gone">Other</a>`),
+			})
+			opts := testOptions(t)
+			opts.ignore = ignoring(t, tt.patterns...)
+			_, rows := testCrawl(t, site, "http://example.com/", opts)
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			// Ignored links aren't checked either.
+			if n := site.count("GET", "http://example.com/old/gone") + site.count("HEAD", "http://other.test/gone"); len(tt.patterns) > 0 && n != 0 {
+				t.Errorf("got %d requests for the ignored links, want none", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import "sync"
+
+// crawlIsolated crawls each of the hosts at the same time, for
+// -isolate-hosts, each with a collector and results of its own, so that a
+// slow or failing host doesn't hold up or trip the breaker for the rest.
+// Each host gets the whole of -max-visits. The request budget, the CSV
+// stream, the event log and the checks are still shared, so that a check
+// like -check-descriptions compares pages across hosts. Once they're all done,
+// everything they found is merged into res.
+func crawlIsolated(res *results, opts *options) error {
+	parts := make([]*results, 0, len(opts.hosts))
+	errs := make([]error, len(opts.hosts))
+	checks := res.enabledChecks(opts)
+	var wg sync.WaitGroup
+	for i, host := range opts.hosts {
+		part := newResults()
+		part.checks = checks
+		part.budget = res.budget
+		part.stream = res.stream
+		part.events = res.events
+		if res.breaker != nil {
+			part.breaker = &circuitBreaker{maxTotal: res.breaker.maxTotal, maxConsecutive: res.breaker.maxConsecutive}
+		}
+		parts = append(parts, part)
+
+		hostOpts := *opts
+		hostOpts.hosts = stringList{host}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = crawl(part, &hostOpts)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, part := range parts {
+		res.merge(part)
+	}
+	return nil
+}
+
+// merge adds everything in other to res. Where both have something for
+// the same URL, e.g. an external link both hosts link to, other wins.
+func (res *results) merge(other *results) {
+	res.Lock()
+	defer res.Unlock()
+	other.Lock()
+	defer other.Unlock()
+
+	if other.breaker != nil && other.breaker.tripped() != "" && (res.breaker == nil || res.breaker.tripped() == "") {
+		res.breaker = other.breaker
+	}
+	for source, links := range other.pages {
+		if _, ok := res.pages[source]; !ok {
+			res.pages[source] = map[string]string{}
+		}
+		for link, element := range links {
+			res.pages[source][link] = element
+		}
+	}
+	for source, counts := range other.linkCounts {
+		if _, ok := res.linkCounts[source]; !ok {
+			res.linkCounts[source] = map[string]int{}
+		}
+		for link, count := range counts {
+			res.linkCounts[source][link] += count
+		}
+	}
+	for key, links := range other.linkIndex {
+		res.linkIndex[key] = append(res.linkIndex[key], links...)
+	}
+	for page, depth := range other.depths {
+		if current, ok := res.depths[page]; !ok || depth < current {
+			res.depths[page] = depth
+		}
+	}
+	res.findings = append(res.findings, other.findings...)
+
+	for _, m := range [][2]map[string]string{
+		{res.canonicalHosts, other.canonicalHosts},
+		{res.errors, other.errors},
+		{res.finalURLs, other.finalURLs},
+		{res.invalid, other.invalid},
+		{res.lastModified, other.lastModified},
+		{res.methods, other.methods},
+		{res.redirects, other.redirects},
+		{res.requestIDs, other.requestIDs},
+	} {
+		for k, v := range m[1] {
+			m[0][k] = v
+		}
+	}
+	for _, m := range [][2]map[string]int{
+		{res.heads, other.heads},
+		{res.hostVisits, other.hostVisits},
+		{res.refreshes, other.refreshes},
+		{res.sizes, other.sizes},
+	} {
+		for k, v := range m[1] {
+			m[0][k] = v
+		}
+	}
+	for _, m := range [][2]map[string]bool{
+		{res.assumedOK, other.assumedOK},
+		{res.rangeFailed, other.rangeFailed},
+		{res.skipped, other.skipped},
+		{res.tooSlow, other.tooSlow},
+	} {
+		for k, v := range m[1] {
+			m[0][k] = v
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// fill gives v a value which isn't the zero value, with one entry in each
+// map and slice all the way down.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key)
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fill(elem)
+		m.SetMapIndex(key, elem)
+		v.Set(m)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0))
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i))
+		}
+	case reflect.String:
+		v.SetString("http://example.com/")
+	case reflect.Int:
+		v.SetInt(1)
+	case reflect.Bool:
+		v.SetBool(true)
+	}
+}
+
+// TestMergeCoversEveryField fills in every field of the results for one
+// host, so that a new field which merge doesn't know about shows up here
+// rather than as something missing from the -isolate-hosts report.
+func TestMergeCoversEveryField(t *testing.T) {
+	// crawlIsolated hands these to every host, so there's nothing to merge.
+	shared := map[string]bool{"budget": true, "checks": true, "events": true, "stream": true}
+
+	other := newResults()
+	v := reflect.ValueOf(other).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous || shared[field.Name] {
+			continue
+		}
+		if field.Type.Kind() == reflect.Ptr {
+			// The breaker is checked by TestMergeBreaker.
+			continue
+		}
+		f := v.Field(i)
+		fill(reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem())
+	}
+
+	res := newResults()
+	res.merge(other)
+
+	got := reflect.ValueOf(res).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous || shared[field.Name] || field.Type.Kind() == reflect.Ptr {
+			continue
+		}
+		want := reflect.NewAt(field.Type, unsafe.Pointer(v.Field(i).UnsafeAddr())).Elem().Interface()
+		merged := reflect.NewAt(field.Type, unsafe.Pointer(got.Field(i).UnsafeAddr())).Elem().Interface()
+		if !reflect.DeepEqual(merged, want) {
+			t.Errorf("merge doesn't copy %v: got %v, want %v", field.Name, merged, want)
+		}
+	}
+}
+
+func TestMergeBreaker(t *testing.T) {
+	tripped := func() *circuitBreaker { return &circuitBreaker{reason: "too many failures"} }
+	tests := []struct {
+		name  string
+		res   *circuitBreaker
+		other *circuitBreaker
+		want  string
+	}{
+		{name: "neither tripped", res: &circuitBreaker{}, other: &circuitBreaker{}},
+		{name: "the other host tripped", res: &circuitBreaker{}, other: tripped(), want: "too many failures"},
+		{name: "no breaker of our own", other: tripped(), want: "too many failures"},
+		{name: "we tripped first", res: &circuitBreaker{reason: "first"}, other: tripped(), want: "first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			res.breaker = tt.res
+			other := newResults()
+			other.breaker = tt.other
+			res.merge(other)
+
+			got := ""
+			if res.breaker != nil {
+				got = res.breaker.tripped()
+			}
+			if got != tt.want {
+				t.Errorf("got breaker tripped %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDepths(t *testing.T) {
+	res := newResults()
+	res.depths["http://example.com/a"] = 1
+	res.depths["http://example.com/b"] = 3
+	res.addLink("http://example.com/", "http://example.com/a", "a")
+
+	other := newResults()
+	other.depths["http://example.com/a"] = 2
+	other.depths["http://example.com/b"] = 2
+	other.addLink("http://example.com/", "http://example.com/b", "a")
+	res.merge(other)
+
+	want := map[string]int{"http://example.com/a": 1, "http://example.com/b": 2}
+	if !reflect.DeepEqual(res.depths, want) {
+		t.Errorf("got depths %v, want the shallowest of each, %v", res.depths, want)
+	}
+	if links := res.pages["http://example.com/"]; len(links) != 2 {
+		t.Errorf("got links %v on the home page, want both hosts' links", links)
+	}
+	if counts := res.linkCounts["http://example.com/"]; len(counts) != 2 {
+		t.Errorf("got link counts %v for the home page, want both hosts' links", counts)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const jsonldSelector = `script[type="application/ld+json"]`
+
+// jsonldLinks returns the URLs in a JSON-LD block, e.g. the url, image and
+// sameAs of an Organization. Any absolute http or https string counts,
+// since schema.org has far too many URL-valued properties to list. Keywords
+// like @context and @id name things rather than link to them, so they're
+// left out, but @graph holds the things themselves.
+func jsonldLinks(text string) ([]string, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return nil, err
+	}
+
+	links := []string{}
+	var walk func(key string, v interface{})
+	walk = func(key string, v interface{}) {
+		if strings.HasPrefix(key, "@") && key != "@graph" {
+			return
+		}
+		switch v := v.(type) {
+		case string:
+			if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") {
+				links = append(links, v)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(key, item)
+			}
+		case map[string]interface{}:
+			for k, item := range v {
+				walk(k, item)
+			}
+		}
+	}
+	walk("", data)
+	return links, nil
+}
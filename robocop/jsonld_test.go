@@ -0,0 +1,73 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestJSONLDLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "an organization",
+			text: `{
+				"@context": "https://schema.org",
+				"@type": "Organization",
+				"@id": "https://example.com/#org",
+				"url": "https://example.com/",
+				"logo": {"@type": "ImageObject", "url": "https://example.com/logo.png"},
+				"sameAs": ["https://twitter.com/example", "http://github.com/example"]
+			}`,
+			want: []string{
+				"http://github.com/example",
+				"https://example.com/",
+				"https://example.com/logo.png",
+				"https://twitter.com/example",
+			},
+		},
+		{
+			name: "a graph",
+			text: `{
+				"@context": {"@vocab": "https://schema.org/"},
+				"@graph": [{"@id": "https://example.com/#org", "url": "https://example.com/a"}]
+			}`,
+			want: []string{"https://example.com/a"},
+		},
+		{
+			name: "a list of things",
+			text: `[{"url": "https://example.com/a"}, {"url": "https://example.com/b"}]`,
+			want: []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name: "not URLs",
+			text: `{"name": "Example", "url": "/relative", "email": "mailto:me@example.com", "founded": 2001}`,
+			want: []string{},
+		},
+		{
+			name:    "not JSON",
+			text:    `{"url": "https://example.com/",}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonldLinks(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got links\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/gocolly/colly"
+)
+
+// limitRule is a colly LimitRule for hosts matching a glob, given as
+// glob=parallelism or glob=parallelism/delay, e.g. *.cdn.com=8/100ms
+type limitRule struct {
+	glob        string
+	parallelism int
+	delay       time.Duration
+}
+
+// limitRules implements flag.Value so that -limit-rule can be repeated or
+// given a comma separated list.
+type limitRules []limitRule
+
+func (l *limitRules) String() string {
+	rules := make([]string, 0, len(*l))
+	for _, rule := range *l {
+		rules = append(rules, fmt.Sprintf("%s=%d/%s", rule.glob, rule.parallelism, rule.delay))
+	}
+	return strings.Join(rules, ",")
+}
+
+func (l *limitRules) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not in glob=parallelism/delay format", pair)
+		}
+		if _, err := glob.Compile(parts[0]); err != nil {
+			return fmt.Errorf("bad host glob %q: %v", parts[0], err)
+		}
+
+		settings := strings.SplitN(parts[1], "/", 2)
+		parallelism, err := strconv.Atoi(settings[0])
+		if err != nil || parallelism < 1 {
+			return fmt.Errorf("%q is not a parallelism of 1 or more", settings[0])
+		}
+		rule := limitRule{glob: parts[0], parallelism: parallelism}
+		if len(settings) == 2 {
+			if rule.delay, err = time.ParseDuration(settings[1]); err != nil {
+				return err
+			}
+		}
+		*l = append(*l, rule)
+	}
+	return nil
+}
+
+// collyLimits returns the LimitRules for the collector. colly uses the
+// first rule which matches, so the ones from -limit-rule go ahead of the
+// default rule for each host we crawl.
+func collyLimits(hosts []string, opts *options) []*colly.LimitRule {
+	_, randomDelay := crawlDelay(opts)
+
+	rules := make([]*colly.LimitRule, 0, len(opts.limitRules)+len(hosts))
+	for _, rule := range opts.limitRules {
+		rules = append(rules, &colly.LimitRule{
+			DomainGlob:  rule.glob,
+			Parallelism: rule.parallelism,
+			Delay:       rule.delay,
+			RandomDelay: randomDelay,
+		})
+	}
+	for _, host := range hosts {
+		// e.g. -file without a -base-url
+		if host == "" {
+			continue
+		}
+		rules = append(rules, hostLimit(host, opts))
+	}
+	return rules
+}
+
+// hostLimit returns the default LimitRule for a host we crawl.
+func hostLimit(host string, opts *options) *colly.LimitRule {
+	delay, randomDelay := crawlDelay(opts)
+	return &colly.LimitRule{
+		DomainGlob:  host,
+		Parallelism: 2,
+		Delay:       delay,
+		RandomDelay: randomDelay,
+	}
+}
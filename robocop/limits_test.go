@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLimitRules(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{args: []string{"*.cdn.com=8"}, want: "*.cdn.com=8/0s"},
+		{args: []string{"*.cdn.com=8/100ms"}, want: "*.cdn.com=8/100ms"},
+		{args: []string{"*.cdn.com=8,api.test=1/1s"}, want: "*.cdn.com=8/0s,api.test=1/1s"},
+		{args: []string{"*.cdn.com=8", "api.test=1/1s"}, want: "*.cdn.com=8/0s,api.test=1/1s"},
+		{args: []string{"*.cdn.com"}, wantErr: "not in glob=parallelism/delay format"},
+		{args: []string{"[cdn=8"}, wantErr: "bad host glob"},
+		{args: []string{"*.cdn.com=0"}, wantErr: "not a parallelism of 1 or more"},
+		{args: []string{"*.cdn.com=lots"}, wantErr: "not a parallelism of 1 or more"},
+		{args: []string{"*.cdn.com=8/soon"}, wantErr: "invalid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var rules limitRules
+			var err error
+			for _, arg := range tt.args {
+				if err = rules.Set(arg); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollyLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		hosts []string
+		want  []string
+	}{
+		{
+			name:  "just the hosts we crawl",
+			hosts: []string{"example.com", ""},
+			want:  []string{"example.com 2 0s 0s"},
+		},
+		{
+			name:  "rules go first",
+			args:  []string{"-limit-rule=*.cdn.com=8/100ms", "-limit-rule=*=1"},
+			hosts: []string{"example.com"},
+			want:  []string{"*.cdn.com 8 100ms 0s", "* 1 0s 0s", "example.com 2 0s 0s"},
+		},
+		{
+			name:  "with the crawl delay",
+			args:  []string{"-limit-rule=*.cdn.com=8", "-min-delay=1s", "-max-delay=3s"},
+			hosts: []string{"example.com"},
+			want:  []string{"*.cdn.com 8 0s 2s", "example.com 2 1s 2s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, rule := range collyLimits(tt.hosts, testOptions(t, tt.args...)) {
+				got = append(got, fmt.Sprintf("%s %d %s %s", rule.DomainGlob, rule.Parallelism, rule.Delay, rule.RandomDelay))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got rules\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
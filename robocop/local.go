@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// checkFile HEAD-checks the links in an HTML file on disk, e.g. the output
+// of a static site generator, without crawling anything.
+func checkFile(path string, res *results, opts *options) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := checkHTML(path, file, res, opts); err != nil {
+		log.Fatalf("cannot check %s because %v", path, err)
+	}
+}
+
+// checkHTML HEAD-checks every link in an HTML document which we didn't get
+// from a crawl. Links are recorded against source. Relative links are
+// resolved against -base-url (or the document's <base href>) and skipped if
+// we have neither.
+func checkHTML(source string, r io.Reader, res *results, opts *options) error {
+	contentType := "text/html"
+	if opts.charset != "" {
+		contentType += "; charset=" + opts.charset
+	}
+	r, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return err
+	}
+
+	base, err := url.Parse(opts.baseURL)
+	if err != nil {
+		return err
+	}
+	if href, ok := doc.Find("base[href]").Attr("href"); ok {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+
+	hosts := []string{base.Host}
+	inScope := map[string]bool{base.Host: true}
+	var private *privateHosts
+	if opts.flagPrivateHosts {
+		private = newPrivateHosts()
+	}
+	transport, err := baseTransport(hosts, opts)
+	if err != nil {
+		return err
+	}
+	c, queue, err := makeColly(hosts, res, opts, transport)
+	if err != nil {
+		return err
+	}
+
+	check := func(href, element string) {
+		link, err := base.Parse(href)
+		if err != nil {
+			if opts.verbose {
+				log.Printf("Skipping %v because %v", href, err)
+			}
+			return
+		}
+
+		if !link.IsAbs() {
+			if opts.verbose {
+				log.Printf("Skipping %v as there is no -base-url to resolve it against", href)
+			}
+			return
+		}
+		if !opts.schemes[link.Scheme] {
+			if opts.verbose {
+				log.Printf("Skipping %v", link.String())
+			}
+			res.skipLink(source, link.String(), element)
+			return
+		}
+
+		normalizeURL(link, opts)
+		if !res.screenLink(opts, inScope, private, source, link, element) {
+			return
+		}
+
+		res.addLink(source, link.String(), element)
+		queue.head(c, checkedURL(link.String(), opts))
+	}
+
+	doc.Find(linkSelector + ", " + embedSelector).Each(func(_ int, s *goquery.Selection) {
+		element := goquery.NodeName(s)
+		href, _ := s.Attr(urlAttr(element))
+		check(href, element)
+	})
+
+	for _, spec := range opts.extraSelectors {
+		doc.Find(spec.selector).Each(func(_ int, s *goquery.Selection) {
+			if href, ok := s.Attr(spec.attr); ok && href != "" {
+				check(href, goquery.NodeName(s))
+			}
+		})
+	}
+
+	if opts.checkJSONLD {
+		doc.Find(jsonldSelector).Each(func(_ int, s *goquery.Selection) {
+			links, err := jsonldLinks(s.Text())
+			if err != nil {
+				if opts.verbose {
+					log.Printf("Skipping JSON-LD in %v because %v", source, err)
+				}
+				return
+			}
+			for _, link := range links {
+				check(link, "jsonld")
+			}
+		})
+	}
+
+	queue.wait(c)
+	return nil
+}
+
+// checkURLs HEAD-checks a list of URLs, one per line, and writes
+// URL<tab>STATUS for each of them to w, in the order they were given.
+// Blank lines and lines starting with # are ignored. The exit code follows
+// the same rules as the report's.
+func checkURLs(r io.Reader, w io.Writer, res *results, opts *options) (int, error) {
+	var links []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		link, err := url.Parse(line)
+		if err != nil || !link.IsAbs() {
+			if opts.verbose {
+				log.Printf("Skipping %v as it is not an absolute URL", line)
+			}
+			continue
+		}
+		normalizeURL(link, opts)
+		links = append(links, link.String())
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := headLinks(links, res, opts); err != nil {
+		return 0, err
+	}
+
+	code := 0
+	for _, link := range links {
+		_, status := res.linkStatus(link, checkedURL(link, opts))
+		if status == "" {
+			status = "error"
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", link, status); err != nil {
+			return 0, err
+		}
+
+		if failsRun(status, statusSeverity(status, opts), opts) {
+			code = 1
+		}
+	}
+	return code, nil
+}
+
+// headLinks HEAD-checks a list of absolute links, without crawling
+// anything, and runs -validate-cmd on them. Links to -assume-ok-hosts
+// aren't checked.
+func headLinks(links []string, res *results, opts *options) error {
+	hosts := []string{}
+	seen := map[string]bool{}
+	checked := []string{}
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err == nil && isAssumedOK(u.Host, opts.assumeOKHosts) {
+			res.Lock()
+			res.assumedOK[link] = true
+			res.Unlock()
+			continue
+		}
+		checked = append(checked, link)
+		if err == nil && !seen[u.Host] {
+			seen[u.Host] = true
+			hosts = append(hosts, u.Host)
+		}
+	}
+
+	base, err := baseTransport(hosts, opts)
+	if err != nil {
+		return err
+	}
+	c, queue, err := makeColly(hosts, res, opts, base)
+	if err != nil {
+		return err
+	}
+	for _, link := range checked {
+		queue.head(c, checkedURL(link, opts))
+	}
+	queue.wait(c)
+	validateLinks(res, links, opts)
+	return nil
+}
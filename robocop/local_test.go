@@ -0,0 +1,226 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveSite serves pages, which are keyed by path rather than URL, over
+// HTTP for the code which makes its own transport, like checkHTML. The
+// stub site counts the requests, e.g. "HEAD /gone".
+func serveSite(t *testing.T, pages map[string]stubPage) (*httptest.Server, *stubSite) {
+	t.Helper()
+	site := newStubSite(pages)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := r.Clone(r.Context())
+		req.URL = &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		resp, _ := site.RoundTrip(req)
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server, site
+}
+
+func TestCheckHTML(t *testing.T) {
+	server, site := serveSite(t, map[string]stubPage{
+		"/fine": {status: 200},
+	})
+	base := server.URL
+	// The same server under another name, so that it's another host.
+	external := strings.Replace(base, "127.0.0.1", "localhost", 1)
+
+	tests := []struct {
+		name      string
+		args      []string
+		ignore    []string
+		want      []string
+		notHEADed []string
+	}{
+		{
+			name: "failures",
+			want: []string{base + "/gone 404", base + "/old/page 404", external + "/gone 404"},
+		},
+		{
+			// Only links elsewhere count, so localhost is private and
+			// 127.0.0.1 isn't.
+			name: "private hosts",
+			args: []string{"-flag-private-hosts"},
+			want: []string{
+				base + "/gone 404",
+				base + "/old/page 404",
+				external + "/gone 404",
+				external + "/gone private-host",
+			},
+		},
+		{
+			name: "no external links",
+			args: []string{"-no-external"},
+			want: []string{base + "/gone 404", base + "/old/page 404"},
+		},
+		{
+			name:      "ignored links",
+			ignore:    []string{base + "/gone", base + "/old/*", external + "/*"},
+			want:      []string{},
+			notHEADed: []string{"/gone", "/old/page"},
+		},
+		{
+			name: "assumed ok",
+			args: []string{"-assume-ok-hosts=127.0.0.1,localhost", "-all"},
+			want: []string{
+				base + "/fine assumed-ok",
+				base + "/gone assumed-ok",
+				base + "/old/page assumed-ok",
+				external + "/gone assumed-ok",
+			},
+			notHEADed: []string{"/fine", "/gone", "/old/page"},
+		},
+	}
+
+	body := `<html><body>
+		<a href="/fine">Fine</a>
+		<a href="/gone">Gone</a>
+		<a href="/old/page">Old</a>
+		<a href="` + external + `/gone">Elsewhere</a>
+		</body></html>`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			site.requests = map[string]int{}
+			opts := testOptions(t, append([]string{"-base-url=" + base}, tt.args...)...)
+			opts.ignore = ignoring(t, tt.ignore...)
+			res := newResults()
+			if err := checkHTML("index.html", strings.NewReader(body), res, opts); err != nil {
+				t.Fatal(err)
+			}
+
+			got := reported(finishReport(res, opts))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, path := range tt.notHEADed {
+				if n := site.count("HEAD", path); n != 0 {
+					t.Errorf("got %d HEADs of %v, want none", n, path)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	server, _ := serveSite(t, map[string]stubPage{
+		"/fine": {status: 200},
+	})
+	base := server.URL
+
+	tests := []struct {
+		name string
+		body string
+		args []string
+		want []string
+	}{
+		{
+			name: "broken absolute link",
+			body: `<a href="` + base + `/fine">Fine</a><a href="` + base + `/gone">Gone</a>`,
+			want: []string{base + "/gone 404"},
+		},
+		{
+			name: "relative links without -base-url",
+			body: `<a href="/gone">Gone</a>`,
+			want: []string{},
+		},
+		{
+			name: "relative links with -base-url",
+			body: `<a href="/fine">Fine</a><a href="/gone">Gone</a>`,
+			args: []string{"-base-url=" + base},
+			want: []string{base + "/gone 404"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			path := filepath.Join(t.TempDir(), "index.html")
+			if err := os.WriteFile(path, []byte("<html><body>"+tt.body+"</body></html>"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			opts := testOptions(t, append([]string{"-file=" + path}, tt.args...)...)
+			res := newResults()
+			checkFile(opts.file, res, opts)
+
+			rows := finishReport(res, opts)
+			got := reported(rows)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, row := range rows {
+				if row[sourceColumn] != path {
+					t.Errorf("got %v found on %v, want %v", row[linkColumn], row[sourceColumn], path)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	server, site := serveSite(t, map[string]stubPage{
+		"/fine": {status: 200},
+	})
+	base := server.URL
+
+	tests := []struct {
+		name  string
+		args  []string
+		links []string
+		want  []string
+		code  int
+	}{
+		{
+			name:  "failures",
+			links: []string{base + "/fine", base + "/gone"},
+			want:  []string{base + "/fine\t200", base + "/gone\t404"},
+			code:  1,
+		},
+		{
+			// assumed.test doesn't resolve, so it would be a dns-error if
+			// we checked it.
+			name:  "assumed ok",
+			args:  []string{"-assume-ok-hosts=assumed.test"},
+			links: []string{base + "/fine", "http://assumed.test/gone", "http://www.assumed.test/"},
+			want: []string{
+				base + "/fine\t200",
+				"http://assumed.test/gone\tassumed-ok",
+				"http://www.assumed.test/\tassumed-ok",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			var out strings.Builder
+			code, err := checkURLs(strings.NewReader(strings.Join(tt.links, "\n")), &out, newResults(), testOptions(t, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(out.String()); got != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", got, strings.Join(tt.want, "\n"))
+			}
+			if code != tt.code {
+				t.Errorf("got exit code %d, want %d", code, tt.code)
+			}
+		})
+	}
+	if got := site.count("HEAD", "/gone"); got != 1 {
+		t.Errorf("got %d HEADs of /gone, want 1", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// formFields implements flag.Value for key=value form fields. Values may
+// contain commas, e.g. in passwords, so each field needs its own flag:
+// -login-fields=user=me -login-fields=password=a,b
+type formFields url.Values
+
+func (f formFields) String() string {
+	// Don't print the values, they're likely to be passwords.
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func (f formFields) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("%q is not in key=value format", value)
+	}
+	url.Values(f).Add(parts[0], parts[1])
+	return nil
+}
+
+// login POSTs the login form and returns a cookie jar holding the session
+// it hands back. We follow any redirects, since logins usually send you
+// off somewhere else, and check the page we land on for successSelector so
+// that we don't crawl a whole site as a logged out user by mistake.
+func login(client *http.Client, userAgent, loginURL string, fields formFields, successSelector string) (*cookiejar.Jar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", loginURL, strings.NewReader(url.Values(fields).Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+
+	loginClient := &http.Client{Transport: client.Transport, Jar: jar}
+	resp, err := loginClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s returned %d", loginURL, resp.StatusCode)
+	}
+
+	if successSelector != "" {
+		doc, err := goquery.NewDocumentFromReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Find(successSelector).Length() == 0 {
+			return nil, fmt.Errorf("%q not found on %s, check the login fields", successSelector, resp.Request.URL)
+		}
+	}
+	return jar, nil
+}
+
+// When at least sessionLostMinLinks links, and sessionLostFraction of all
+// the links we checked, redirect to the same place, it's usually the login
+// page and we've lost our session.
+const (
+	sessionLostMinLinks = 10
+	sessionLostFraction = 0.5
+)
+
+// sessionLost returns the URL which most of the links we checked redirect
+// to, along with how many do, if that looks like a login page. Otherwise
+// it returns "".
+func sessionLost(res *results) (string, int) {
+	// We only have redirects when we're not following them, and only
+	// have final URLs when we are.
+	targets := map[string]int{}
+	for _, target := range res.redirects {
+		if target != "" {
+			targets[target]++
+		}
+	}
+	for _, target := range res.finalURLs {
+		targets[target]++
+	}
+
+	best, count := "", 0
+	for target, n := range targets {
+		if n > count || (n == count && target < best) {
+			best, count = target, n
+		}
+	}
+	if count < sessionLostMinLinks || float64(count) < sessionLostFraction*float64(len(res.heads)) {
+		return "", 0
+	}
+	return best, count
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFormFields(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{args: []string{"user=me"}, want: "user=me"},
+		{args: []string{"user=me", "password=a,b=c"}, want: "password=a%2Cb%3Dc&user=me"},
+		{args: []string{"empty="}, want: "empty="},
+		{args: []string{"user"}, wantErr: true},
+		{args: []string{"=me"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			fields := formFields{}
+			var err error
+			for _, arg := range tt.args {
+				if err = fields.Set(arg); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if got := url.Values(fields).Encode(); !tt.wantErr && got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	session := map[string]string{"Set-Cookie": "session=abc; Path=/"}
+	tests := []struct {
+		name     string
+		pages    map[string]stubPage
+		selector string
+		wantErr  string
+	}{
+		{
+			name:  "logged in",
+			pages: map[string]stubPage{"http://example.com/login": {status: 200, header: session}},
+		},
+		{
+			name: "the page we land on has the selector",
+			pages: map[string]stubPage{
+				"http://example.com/login":   {status: 302, location: "/account", header: session},
+				"http://example.com/account": html(`<a class="logout" href="/logout">Log out</a>`),
+			},
+			selector: ".logout",
+		},
+		{
+			name: "without the selector",
+			pages: map[string]stubPage{
+				"http://example.com/login":   {status: 302, location: "/account", header: session},
+				"http://example.com/account": html(`<form action="/login"></form>`),
+			},
+			selector: ".logout",
+			wantErr:  `".logout" not found on http://example.com/account`,
+		},
+		{
+			name:    "refused",
+			pages:   map[string]stubPage{"http://example.com/login": {status: 403}},
+			wantErr: "http://example.com/login returned 403",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newStubSite(tt.pages)
+			fields := formFields{}
+			fields.Set("user=me")
+			jar, err := login(&http.Client{Transport: site}, "robocop", "http://example.com/login", fields, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if site.count("POST", "http://example.com/login") != 1 {
+				t.Errorf("got requests %v, want a POST to the login page", site.requests)
+			}
+			u, _ := url.Parse("http://example.com/")
+			if cookies := jar.Cookies(u); len(cookies) != 1 || cookies[0].String() != "session=abc" {
+				t.Errorf("got cookies %v, want the session", cookies)
+			}
+		})
+	}
+}
+
+func TestSessionLost(t *testing.T) {
+	tests := []struct {
+		name       string
+		links      int
+		redirected int
+		following  bool
+		want       int
+	}{
+		{name: "nothing redirects", links: 20},
+		{name: "most links redirect", links: 20, redirected: 15, want: 15},
+		{name: "when we follow redirects", links: 20, redirected: 15, following: true, want: 15},
+		{name: "not enough of them", links: 20, redirected: 9},
+		{name: "not enough links", links: 9, redirected: 9},
+		{name: "exactly half", links: 20, redirected: 10, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			for i := 0; i < tt.links; i++ {
+				link := fmt.Sprintf("http://example.com/%d", i)
+				res.heads[link] = 200
+				target := ""
+				if i < tt.redirected {
+					res.heads[link] = 302
+					target = "http://example.com/login"
+				}
+				if tt.following {
+					if target != "" {
+						res.finalURLs[link] = target
+					}
+					continue
+				}
+				res.redirects[link] = target
+			}
+
+			target, count := sessionLost(res)
+			if count != tt.want {
+				t.Errorf("got %d links to %q, want %d", count, target, tt.want)
+			}
+			if count > 0 && target != "http://example.com/login" {
+				t.Errorf("got target %q, want the login page", target)
+			}
+		})
+	}
+}
+
+func TestSessionLostReport(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "logged in", args: []string{"-login-url=http://example.com/login"}, want: true},
+		{name: "no login", args: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			res := newResults()
+			for i := 0; i < 20; i++ {
+				link := fmt.Sprintf("http://example.com/%d", i)
+				res.heads[link] = 302
+				res.redirects[link] = "http://example.com/login"
+			}
+
+			got := false
+			for _, row := range finishReport(res, testOptions(t, tt.args...)) {
+				if row[statusColumn] == "session-lost" {
+					got = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("got a session-lost row %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// mailtoCheck flags mailto: links whose addresses don't parse, e.g. ones
+// missing an @ or containing spaces. We can't HEAD these, but we can at
+// least make sure they're well formed.
+type mailtoCheck struct{}
+
+func (mailtoCheck) Name() string {
+	return "mailto"
+}
+
+func (mailtoCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	findings := []Finding{}
+	doc.Find(linkSelector).Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		href = strings.TrimSpace(href)
+		if !strings.HasPrefix(strings.ToLower(href), "mailto:") {
+			return
+		}
+		if !validMailto(href) {
+			findings = append(findings, Finding{
+				Page:   page.Request.URL.String(),
+				Link:   href,
+				Status: "bad-mailto",
+			})
+		}
+	})
+	return findings
+}
+
+// validMailto reports whether every address in a mailto: link parses. A
+// link with no addresses at all, like mailto:?subject=hi, is allowed.
+func validMailto(href string) bool {
+	to := href[len("mailto:"):]
+	if i := strings.Index(to, "?"); i != -1 {
+		to = to[:i]
+	}
+	to, err := url.PathUnescape(to)
+	if err != nil {
+		return false
+	}
+	if to == "" {
+		return true
+	}
+
+	for _, addr := range strings.Split(to, ",") {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(addr))
+		// ParseAddress accepts "Name <a@b.com>", which isn't what belongs
+		// in a mailto link.
+		if err != nil || parsed.Name != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestValidMailto(t *testing.T) {
+	tests := []struct {
+		href string
+		want bool
+	}{
+		{href: "mailto:me@example.com", want: true},
+		{href: "mailto:me@example.com,you@example.com", want: true},
+		{href: "mailto:me@example.com?subject=hi", want: true},
+		{href: "mailto:me%40example.com", want: true},
+		{href: "mailto:?subject=hi", want: true},
+		{href: "mailto:me.example.com"},
+		{href: "mailto:me @example.com"},
+		{href: "mailto:Me <me@example.com>"},
+		{href: "mailto:me@example.com,"},
+		{href: "mailto:%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			if got := validMailto(tt.href); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailtoCheck(t *testing.T) {
+	page, doc := testPage(t, "https://example.com/", `
+		<a href="mailto:me@example.com">ok</a>
+		<a href=" MAILTO:broken ">broken</a>
+		<a href="/contact">not mail</a>`)
+	findings := mailtoCheck{}.Run(page, doc)
+	if len(findings) != 1 || findings[0].Link != "MAILTO:broken" || findings[0].Status != "bad-mailto" {
+		t.Errorf("got %+v, want the one bad-mailto", findings)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runMeta describes a run, for -output-timestamp, so that an archived
+// report says where and when it came from.
+type runMeta struct {
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Hosts    []string  `json:"hosts"`
+	Version  string    `json:"version"`
+	Pages    int       `json:"pages"`
+}
+
+// newRunMeta describes a run which started at started and has just
+// finished. Pages is the number of pages we fetched with a GET.
+func newRunMeta(started time.Time, res *results, opts *options) *runMeta {
+	hosts := append([]string{}, opts.hosts...)
+	if opts.file != "" {
+		hosts = append(hosts, opts.file)
+	}
+	return &runMeta{
+		Started:  started.UTC(),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+		Hosts:    hosts,
+		Version:  toolVersion(),
+		Pages:    len(res.sizes),
+	}
+}
+
+// toolVersion is the module version we were built from, or the VCS
+// revision for a build from a checkout.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return "devel"
+}
+
+// writeComments writes the metadata as # comment lines, which is how we
+// put it at the top of a CSV report.
+func (m *runMeta) writeComments(w io.Writer) error {
+	lines := [][2]string{
+		{"started", m.Started.Format(time.RFC3339)},
+		{"duration", m.Duration},
+		{"hosts", strings.Join(m.Hosts, " ")},
+		{"version", m.Version},
+		{"pages", strconv.Itoa(m.Pages)},
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(w, "# %s: %s\n", line[0], line[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
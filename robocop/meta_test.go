@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRunMeta(t *testing.T) {
+	started := time.Date(2026, 3, 14, 9, 26, 53, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name  string
+		hosts []string
+		file  string
+		want  string
+	}{
+		{name: "a crawl", hosts: []string{"https://example.com/", "https://other.test/"}, want: "[https://example.com/ https://other.test/]"},
+		{name: "a file", hosts: []string{"https://example.com/"}, file: "page.html", want: "[https://example.com/ page.html]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			res.sizes["https://example.com/"] = 100
+			res.sizes["https://example.com/about"] = 100
+			opts := &options{hosts: tt.hosts, file: tt.file}
+
+			m := newRunMeta(started, res, opts)
+			if !m.Started.Equal(started) || m.Started.Location() != time.UTC {
+				t.Errorf("got started %v, want %v in UTC", m.Started, started)
+			}
+			if got := fmt.Sprint(m.Hosts); got != tt.want {
+				t.Errorf("got hosts %v, want %v", got, tt.want)
+			}
+			if m.Pages != 2 || m.Version == "" {
+				t.Errorf("got %d pages and version %q", m.Pages, m.Version)
+			}
+			if len(opts.hosts) != len(tt.hosts) {
+				t.Errorf("changed the hosts to %v", opts.hosts)
+			}
+		})
+	}
+}
+
+func TestWriteComments(t *testing.T) {
+	m := &runMeta{
+		Started:  time.Date(2026, 3, 14, 8, 26, 53, 0, time.UTC),
+		Duration: "1m2.5s",
+		Hosts:    []string{"https://example.com/", "https://other.test/"},
+		Version:  "v1.2.3",
+		Pages:    42,
+	}
+	var out bytes.Buffer
+	if err := m.writeComments(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "# started: 2026-03-14T08:26:53Z\n" +
+		"# duration: 1m2.5s\n" +
+		"# hosts: https://example.com/ https://other.test/\n" +
+		"# version: v1.2.3\n" +
+		"# pages: 42\n"
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// mixedContentCheck flags https pages which load resources over http.
+// Browsers block or warn about these.
+type mixedContentCheck struct{}
+
+func (mixedContentCheck) Name() string {
+	return "mixed"
+}
+
+func (mixedContentCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	if page.Request.URL.Scheme != "https" {
+		return nil
+	}
+
+	findings := []Finding{}
+	doc.Find("img[src], script[src], iframe[src], audio[src], video[src], source[src], link[rel=stylesheet][href]").Each(
+		func(_ int, s *goquery.Selection) {
+			attr := "src"
+			if goquery.NodeName(s) == "link" {
+				attr = "href"
+			}
+			value, _ := s.Attr(attr)
+			link, err := page.Request.URL.Parse(value)
+			if err != nil || link.Scheme != "http" {
+				return
+			}
+			findings = append(findings, Finding{
+				Page:   page.Request.URL.String(),
+				Link:   link.String(),
+				Status: "mixed-content",
+			})
+		},
+	)
+	return findings
+}
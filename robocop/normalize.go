@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeURL rewrites a link we found into the form we store and visit,
+// so that equivalent URLs collapse into a single entry.
+func normalizeURL(u *url.URL, opts *options) {
+	// Hosts aren't case sensitive and the default port can go, so that
+	// we only HEAD an external link once however it's written.
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+u.Port())
+	}
+
+	if opts.normalizeUnicode {
+		u.Host = asciiHost(u)
+		normalizePathEscapes(u)
+	}
+
+	if opts.sortQuery {
+		u.RawQuery = sortQuery(u.RawQuery)
+	}
+}
+
+// asciiHost converts an international domain name to punycode, so that
+// bücher.example and xn--bcher-kva.example are the same host. Anything
+// idna won't convert is left alone.
+func asciiHost(u *url.URL) string {
+	if net.ParseIP(u.Hostname()) != nil {
+		return u.Host
+	}
+	ascii, err := idna.Lookup.ToASCII(u.Hostname())
+	if err != nil {
+		return u.Host
+	}
+	if port := u.Port(); port != "" {
+		return ascii + ":" + port
+	}
+	return ascii
+}
+
+// normalizePathEscapes rewrites the escapes in a path the way RFC 3986
+// says to compare them: escaped unreserved characters are decoded and the
+// rest use upper case hex. /a%7eb, /a~b and /a%7Eb all become /a~b, while
+// %2f stays escaped as %2F, since it isn't the same as a slash.
+func normalizePathEscapes(u *url.URL) {
+	escaped := u.EscapedPath()
+	if !strings.Contains(escaped, "%") {
+		return
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '%' || i+2 >= len(escaped) {
+			b.WriteByte(escaped[i])
+			continue
+		}
+		hex := strings.ToUpper(escaped[i+1 : i+3])
+		c, err := url.PathUnescape("%" + hex)
+		if err != nil {
+			b.WriteByte(escaped[i])
+			continue
+		}
+		if isUnreserved(c[0]) {
+			b.WriteString(c)
+		} else {
+			b.WriteString("%" + hex)
+		}
+		i += 2
+	}
+
+	path, err := url.PathUnescape(b.String())
+	if err != nil {
+		return
+	}
+	u.Path = path
+	u.RawPath = b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// checkedURL returns the URL we actually request for a link. The link
+// itself keeps its fragment, since #a and #b are different anchors, but
+// with -ignore-fragments they're the same resource and only need checking
+// once.
+func checkedURL(link string, opts *options) string {
+	if !opts.ignoreFragments {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Fragment == "" && u.RawFragment == "") {
+		return link
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// sortQuery orders query parameters by key, so ?b=2&a=1 and ?a=1&b=2 are
+// the same link. The sort is stable, so repeated keys keep their relative
+// order; ?a=2&a=1 means something different than ?a=1&a=2 to most apps.
+// We work on the raw string rather than url.Values so that we don't
+// re-encode anything.
+func sortQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	key := func(param string) string {
+		return strings.SplitN(param, "=", 2)[0]
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return key(params[i]) < key(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// wwwVariants is true if a and b are the same host with and without www.
+func wwwVariants(a, b string) bool {
+	return a == "www."+b || b == "www."+a
+}
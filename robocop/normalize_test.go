@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		link string
+		opts options
+		want string
+	}{
+		{link: "http://example.com/x", want: "http://example.com/x"},
+		{link: "HTTP://Example.COM/x", want: "http://example.com/x"},
+		{link: "http://example.com:80/x", want: "http://example.com/x"},
+		{link: "https://example.com:443/x", want: "https://example.com/x"},
+		{link: "http://example.com:443/x", want: "http://example.com:443/x"},
+		{link: "http://example.com/?b=2&a=1", want: "http://example.com/?b=2&a=1"},
+		{link: "http://example.com/?b=2&a=1", opts: options{sortQuery: true}, want: "http://example.com/?a=1&b=2"},
+		{link: "http://example.com/a%7eb", opts: options{normalizeUnicode: true}, want: "http://example.com/a~b"},
+		{link: "http://example.com/a%2fb", opts: options{normalizeUnicode: true}, want: "http://example.com/a%2Fb"},
+		{link: "http://bücher.example/", opts: options{normalizeUnicode: true}, want: "http://xn--bcher-kva.example/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			u, err := url.Parse(tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			normalizeURL(u, &tt.opts)
+			if got := u.String(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalLinkHeadedOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []string
+	}{
+		{
+			name: "written the same way",
+			links: []string{
+				"http://other.test/x",
+				"http://other.test/x",
+				"http://other.test/x",
+				"http://other.test/x",
+				"http://other.test/x",
+			},
+		},
+		{
+			name: "written differently",
+			links: []string{
+				"http://other.test/x",
+				"HTTP://other.test/x",
+				"http://Other.Test/x",
+				"http://other.test:80/x",
+				"//other.test/x",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := ""
+			pages := map[string]stubPage{"http://other.test/x": {status: 200}}
+			for i, link := range tt.links {
+				page := "http://example.com/" + string(rune('a'+i))
+				home += `<a href="` + page + `">page</a>`
+				pages[page] = html(`<a href="` + link + `">x</a>`)
+			}
+			pages["http://example.com/"] = html(home)
+			site := newStubSite(pages)
+
+			res, _ := testCrawl(t, site, "http://example.com/", testOptions(t))
+			if got := site.count("HEAD", "http://other.test/x"); got != 1 {
+				t.Errorf("got %d HEADs, want 1", got)
+			}
+			if got := site.count("GET", "http://other.test/x"); got != 0 {
+				t.Errorf("got %d GETs, want none", got)
+			}
+			for i := range tt.links {
+				page := "http://example.com/" + string(rune('a'+i))
+				if _, ok := res.pages[page]["http://other.test/x"]; !ok {
+					t.Errorf("%v doesn't link to http://other.test/x: %v", page, res.pages[page])
+				}
+			}
+		})
+	}
+}
+
+func TestIgnoreFragments(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="http://other.test/a#x">X</a><a href="http://other.test/a#y">Y</a>`),
+		"http://other.test/a": {status: 200},
+	})
+
+	tests := []struct {
+		name  string
+		args  []string
+		heads int
+	}{
+		{name: "ignored", heads: 1},
+		{name: "not ignored", args: []string{"-ignore-fragments=false"}, heads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.requests = map[string]int{}
+			res, _ := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			heads := 0
+			for request, n := range site.requests {
+				if strings.HasPrefix(request, "HEAD http://other.test/a") {
+					heads += n
+				}
+			}
+			if heads != tt.heads {
+				t.Errorf("got %d HEADs in %v, want %d", heads, site.requests, tt.heads)
+			}
+			// Either way, both anchors are still there for checking.
+			for _, link := range []string{"http://other.test/a#x", "http://other.test/a#y"} {
+				if _, ok := res.pages["http://example.com/"][link]; !ok {
+					t.Errorf("got links %v, want %v", res.pages["http://example.com/"], link)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+type pageCount struct {
+	page   string
+	broken int
+}
+
+// brokenPerPage counts the broken links on each page we crawled, worst
+// page first. Pages with nothing broken are left out.
+func brokenPerPage(res *results, opts *options) []pageCount {
+	counts := []pageCount{}
+	for page, links := range res.pages {
+		broken := 0
+		for link := range links {
+			if _, status := res.linkStatus(link, checkedURL(link, opts)); status != "" && statusSeverity(status, opts) == severityError {
+				broken++
+			}
+		}
+		if broken > 0 {
+			counts = append(counts, pageCount{page: page, broken: broken})
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].broken != counts[j].broken {
+			return counts[i].broken > counts[j].broken
+		}
+		return counts[i].page < counts[j].page
+	})
+	return counts
+}
+
+// printPageSummary prints a table of pages and how many broken links are
+// on each, for -page-summary, so you know where to start.
+func printPageSummary(w io.Writer, counts []pageCount) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Source Page", "Broken Links"})
+	for _, c := range counts {
+		table.Append([]string{c.page, strconv.Itoa(c.broken)})
+	}
+	table.Render()
+}
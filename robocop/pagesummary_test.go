@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBrokenPerPage(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		pages map[string][]string
+		want  []string
+	}{
+		{
+			name:  "nothing broken",
+			pages: map[string][]string{"http://example.com/": {"http://example.com/fine"}},
+			want:  []string{},
+		},
+		{
+			name: "worst page first, then by URL",
+			pages: map[string][]string{
+				"http://example.com/":  {"http://example.com/gone", "http://example.com/fine"},
+				"http://example.com/b": {"http://example.com/gone", "http://example.com/error"},
+				"http://example.com/a": {"http://example.com/gone", "http://example.com/moved"},
+				"http://example.com/c": {"http://example.com/fine", "http://example.com/moved"},
+			},
+			want: []string{
+				"http://example.com/b 2",
+				"http://example.com/ 1",
+				"http://example.com/a 1",
+			},
+		},
+		{
+			// A warning isn't broken.
+			name:  "with -warn-status",
+			args:  []string{"-warn-status=404"},
+			pages: map[string][]string{"http://example.com/": {"http://example.com/gone", "http://example.com/error"}},
+			want:  []string{"http://example.com/ 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			res.heads["http://example.com/fine"] = 200
+			res.heads["http://example.com/moved"] = 301
+			res.heads["http://example.com/gone"] = 404
+			res.heads["http://example.com/error"] = 500
+			for page, links := range tt.pages {
+				for _, link := range links {
+					res.addLink(page, link, "a")
+				}
+			}
+
+			got := []string{}
+			counts := brokenPerPage(res, testOptions(t, tt.args...))
+			for _, c := range counts {
+				got = append(got, fmt.Sprintf("%s %d", c.page, c.broken))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			var table bytes.Buffer
+			printPageSummary(&table, counts)
+			for _, c := range counts {
+				if !strings.Contains(table.String(), c.page) {
+					t.Errorf("%s isn't in the table:\n%s", c.page, table.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// isPDF is true for responses which look like PDFs, going by the content
+// type or failing that the extension.
+func isPDF(contentType, link string) bool {
+	if strings.Contains(strings.ToLower(contentType), "application/pdf") {
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(strings.SplitN(link, "?", 2)[0]), ".pdf")
+}
+
+var (
+	pdfURIPattern    = regexp.MustCompile(`/URI\s*(?:\(((?:\\.|[^\\)])*)\)|<([0-9A-Fa-f\s]*)>)`)
+	pdfStreamPattern = regexp.MustCompile(`(?s)stream\r?\n(.*?)endstream`)
+)
+
+// pdfLinks returns the URIs of the link annotations in a PDF. Rather than
+// parse the whole document we look for /URI entries, both in the file
+// itself and in any Flate compressed streams, which is where they end up
+// when a PDF uses object streams.
+func pdfLinks(data []byte) []string {
+	chunks := [][]byte{data}
+	for _, m := range pdfStreamPattern.FindAllSubmatch(data, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			continue
+		}
+		// Trailing bytes before endstream make for an error at the end,
+		// but we still get everything before it.
+		inflated, _ := io.ReadAll(r)
+		chunks = append(chunks, inflated)
+	}
+
+	links := []string{}
+	for _, chunk := range chunks {
+		for _, m := range pdfURIPattern.FindAllSubmatch(chunk, -1) {
+			var uri string
+			if m[2] != nil {
+				b, err := hex.DecodeString(strings.Join(strings.Fields(string(m[2])), ""))
+				if err != nil {
+					continue
+				}
+				uri = string(b)
+			} else {
+				uri = unescapePDFString(string(m[1]))
+			}
+			if uri = strings.TrimSpace(uri); uri != "" {
+				links = append(links, uri)
+			}
+		}
+	}
+	return links
+}
+
+// unescapePDFString undoes the backslash escapes in a PDF literal string.
+func unescapePDFString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
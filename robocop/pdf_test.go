@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strings"
+	"testing"
+)
+
+func TestIsPDF(t *testing.T) {
+	tests := []struct {
+		contentType string
+		link        string
+		want        bool
+	}{
+		{contentType: "application/pdf", link: "http://example.com/report", want: true},
+		{contentType: "Application/PDF; charset=binary", link: "http://example.com/report", want: true},
+		{link: "http://example.com/report.PDF", want: true},
+		{link: "http://example.com/report.pdf?download=1", want: true},
+		{contentType: "text/html", link: "http://example.com/report"},
+		{link: "http://example.com/?file=report.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType+" "+tt.link, func(t *testing.T) {
+			if got := isPDF(tt.contentType, tt.link); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// deflate compresses s the way a PDF's FlateDecode streams are.
+func deflate(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestPDFLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "no links", data: "%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj", want: []string{}},
+		{
+			name: "literal strings",
+			data: "<< /S /URI /URI (http://example.com/a) >>\n<< /S /URI /URI(http://example.com/b) >>",
+			want: []string{"http://example.com/a", "http://example.com/b"},
+		},
+		{
+			name: "escapes",
+			data: `<< /URI (http://example.com/\(a\)\\b) >>`,
+			want: []string{`http://example.com/(a)\b`},
+		},
+		{
+			name: "hex strings",
+			data: "<< /URI <687474703a2f2f 6578616d706c652e636f6d2f> >> << /URI <zz> >>",
+			want: []string{"http://example.com/"},
+		},
+		{
+			name: "empty",
+			data: "<< /URI ( ) >>",
+			want: []string{},
+		},
+		{
+			name: "in an object stream",
+			// Enough of it that it's compressed rather than stored.
+			data: "5 0 obj << /Filter /FlateDecode >> stream\n" +
+				deflate(t, "<< /URI (http://example.com/packed) >>"+strings.Repeat(" 0 0 R", 20)) +
+				"\nendstream",
+			want: []string{"http://example.com/packed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pdfLinks([]byte(tt.data)); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got links\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// pprofHandler serves the net/http/pprof endpoints under /debug/pprof/.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// startPprof serves pprofHandler on addr so that profiles can be captured
+// while a crawl is running. Call the returned function to shut the server
+// down.
+func startPprof(addr string) func() {
+
+	// Listen up front so that a bad address fails before we start crawling.
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("cannot listen on %s for pprof because %v", addr, err)
+	}
+
+	server := &http.Server{Handler: pprofHandler()}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("pprof server stopped because %v", err)
+		}
+	}()
+	log.Printf("serving pprof on http://%s/debug/pprof/", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("cannot shut down pprof server because %v", err)
+		}
+	}
+}
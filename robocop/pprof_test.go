@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// profilingSite is site, except that before answering the first request
+// it fetches profile, so that we know what pprof says partway through a
+// crawl.
+type profilingSite struct {
+	site    *stubSite
+	profile string
+	once    sync.Once
+	status  int
+	body    string
+}
+
+func (s *profilingSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.once.Do(func() {
+		resp, err := http.Get(s.profile)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		s.status, s.body = resp.StatusCode, string(body)
+	})
+	return s.site.RoundTrip(req)
+}
+
+func TestPprof(t *testing.T) {
+	server := httptest.NewServer(pprofHandler())
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/debug/pprof/", want: "goroutine"},
+		{path: "/debug/pprof/goroutine?debug=1", want: "crawl"},
+		{path: "/debug/pprof/cmdline", want: "robocop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			site := &profilingSite{
+				site: newStubSite(map[string]stubPage{
+					"http://example.com/": html(`<a href="/about">About</a>`),
+				}),
+				profile: server.URL + tt.path,
+			}
+			testCrawl(t, site, "http://example.com/", testOptions(t))
+			if site.status != http.StatusOK || !strings.Contains(site.body, tt.want) {
+				t.Errorf("got %d from %v, want 200 and %q in:\n%s", site.status, tt.path, tt.want, site.body)
+			}
+		})
+	}
+}
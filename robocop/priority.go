@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+// sortByPriority sorts report rows by -status-priority. Rows whose status
+// is in the list come first, in the order given. The rest follow, status
+// codes in numeric order and then anything else, like dns-error, in
+// alphabetical order.
+func sortByPriority(rows linkReport, priority []string) {
+	rank := map[string]int{}
+	for i, status := range priority {
+		if _, ok := rank[status]; !ok {
+			rank[status] = i
+		}
+	}
+
+	less := func(a, b string) bool {
+		rankA, listedA := rank[a]
+		rankB, listedB := rank[b]
+		if listedA || listedB {
+			if listedA && listedB {
+				return rankA < rankB
+			}
+			return listedA
+		}
+		codeA, errA := strconv.Atoi(a)
+		codeB, errB := strconv.Atoi(b)
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		if errA == nil && codeA != codeB {
+			return codeA < codeB
+		}
+		return a < b
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a[statusColumn] != b[statusColumn] {
+			return less(a[statusColumn], b[statusColumn])
+		}
+		if a[linkColumn] != b[linkColumn] {
+			return a[linkColumn] < b[linkColumn]
+		}
+		return a[sourceColumn] < b[sourceColumn]
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortByPriority(t *testing.T) {
+	statuses := []string{"dns-error", "500", "404", "timeout", "1000", "301", "404"}
+
+	tests := []struct {
+		name     string
+		priority []string
+		want     string
+	}{
+		{name: "no priority", want: "301 404 404 500 1000 dns-error timeout"},
+		{name: "some first", priority: []string{"timeout", "404"}, want: "timeout 404 404 301 500 1000 dns-error"},
+		{name: "repeated", priority: []string{"500", "dns-error", "500"}, want: "500 dns-error 301 404 404 1000 timeout"},
+		{name: "not in the report", priority: []string{"410"}, want: "301 404 404 500 1000 dns-error timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := linkReport{}
+			for i, status := range statuses {
+				rows = append(rows, reportRow("http://example.com/", "http://example.com/"+string(rune('z'-i)), status))
+			}
+			sortByPriority(rows, tt.priority)
+
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[statusColumn])
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %v, want %v", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestSortByPriorityTies(t *testing.T) {
+	rows := linkReport{
+		reportRow("http://example.com/b", "http://example.com/y", "404"),
+		reportRow("http://example.com/b", "http://example.com/x", "404"),
+		reportRow("http://example.com/a", "http://example.com/y", "404"),
+	}
+	sortByPriority(rows, nil)
+
+	want := []string{
+		"http://example.com/b http://example.com/x",
+		"http://example.com/a http://example.com/y",
+		"http://example.com/b http://example.com/y",
+	}
+	for i, row := range rows {
+		if got := row[sourceColumn] + " " + row[linkColumn]; got != want[i] {
+			t.Errorf("row %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
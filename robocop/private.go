@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// privateHosts remembers which hosts resolve to addresses nobody else can
+// reach, for -flag-private-hosts: loopback, link-local and private
+// networks. Each host is only looked up once.
+type privateHosts struct {
+	sync.Mutex
+	private map[string]bool
+	lookup  func(host string) ([]net.IP, error)
+}
+
+func newPrivateHosts() *privateHosts {
+	return &privateHosts{private: map[string]bool{}, lookup: net.LookupIP}
+}
+
+// isPrivate is true if host, without a port, is or resolves to a private
+// address. Hosts which don't resolve at all are left to the link check.
+func (p *privateHosts) isPrivate(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	p.Lock()
+	private, ok := p.private[host]
+	p.Unlock()
+	if ok {
+		return private
+	}
+
+	var ips []net.IP
+	switch {
+	case host == "localhost" || strings.HasSuffix(host, ".localhost"):
+		private = true
+	case net.ParseIP(host) != nil:
+		ips = []net.IP{net.ParseIP(host)}
+	default:
+		ips, _ = p.lookup(host)
+	}
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			private = true
+		}
+	}
+
+	p.Lock()
+	p.private[host] = private
+	p.Unlock()
+	return private
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
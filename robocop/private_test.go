@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestPrivateHosts(t *testing.T) {
+	addresses := map[string][]net.IP{
+		"intranet.example.com": {net.ParseIP("10.1.2.3")},
+		"public.example.com":   {net.ParseIP("93.184.216.34")},
+		"mixed.example.com":    {net.ParseIP("93.184.216.34"), net.ParseIP("fd00::1")},
+	}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "localhost", want: true},
+		{host: "app.localhost", want: true},
+		{host: "127.0.0.1", want: true},
+		{host: "::1", want: true},
+		{host: "192.168.1.1", want: true},
+		{host: "169.254.169.254", want: true},
+		{host: "0.0.0.0", want: true},
+		{host: "8.8.8.8"},
+		{host: "intranet.example.com", want: true},
+		{host: "Intranet.Example.com.", want: true},
+		{host: "public.example.com"},
+		{host: "mixed.example.com", want: true},
+		{host: "nowhere.invalid"},
+	}
+
+	lookups := map[string]int{}
+	p := newPrivateHosts()
+	p.lookup = func(host string) ([]net.IP, error) {
+		lookups[host]++
+		if ips, ok := addresses[host]; ok {
+			return ips, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if got := p.isPrivate(tt.host); got != tt.want {
+					t.Errorf("got private %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	for host, n := range lookups {
+		if n != 1 {
+			t.Errorf("looked up %s %d times, want once", host, n)
+		}
+	}
+	if _, ok := lookups["127.0.0.1"]; ok {
+		t.Error("looked up an IP address")
+	}
+}
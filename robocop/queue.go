@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// requestQueue caps the number of requests colly has in flight. In async
+// mode colly starts a goroutine for every Visit and Head, and those all sit
+// waiting on the LimitRule, so a large site queues up far more than it can
+// fetch. Once we're at the cap we hold on to the request ourselves and only
+// hand it to colly as earlier requests finish.
+//
+// It also makes sure we only HEAD each URL once. colly only keeps track of
+// the URLs it has visited with GET. With -dedupe-head, known says which
+// URLs we already have a 2xx for some other way, e.g. because we crawled
+// them, so we don't HEAD those at all.
+//
+// External links go through batch, if we're batching them.
+type requestQueue struct {
+	sync.Mutex
+	max      int
+	inFlight int
+	pending  []func() error
+	headed   map[string]bool
+	known    func(link string) bool
+	batch    *headBatch
+}
+
+func (q *requestQueue) visit(c *colly.Collector, link string) {
+	q.add(func() error { return c.Visit(link) })
+}
+
+func (q *requestQueue) head(c *colly.Collector, link string) {
+	q.Lock()
+	if q.headed[link] || (q.known != nil && q.known(link)) {
+		q.Unlock()
+		return
+	}
+	q.headed[link] = true
+	q.Unlock()
+
+	q.add(func() error { return c.Head(link) })
+}
+
+// headExternal HEADs a link to another site, once its batch goes out if
+// we're batching them.
+func (q *requestQueue) headExternal(c *colly.Collector, link string) {
+	if q.batch != nil {
+		q.batch.add(link)
+		return
+	}
+	q.head(c, link)
+}
+
+// wait waits for colly to finish. colly doesn't know about the links
+// waiting for a batch, so we send those and wait again until there are
+// none left.
+func (q *requestQueue) wait(c *colly.Collector) {
+	c.Wait()
+	if q.batch == nil {
+		return
+	}
+	q.batch.close()
+	for q.batch.flush() {
+		c.Wait()
+	}
+}
+
+func (q *requestQueue) add(request func() error) {
+	if q.max <= 0 {
+		_ = request()
+		return
+	}
+
+	q.Lock()
+	if q.inFlight >= q.max {
+		q.pending = append(q.pending, request)
+		q.Unlock()
+		return
+	}
+	q.inFlight++
+	q.Unlock()
+
+	// colly turned it down, e.g. because we've already visited it, so it
+	// never took up a slot.
+	if err := request(); err != nil {
+		q.done()
+	}
+}
+
+// release frees up the slot held by the request with the given context.
+// colly can call OnError after OnResponse for the same request, so we only
+// release each request once.
+func (q *requestQueue) release(ctx *colly.Context) {
+	if q.max <= 0 || ctx.Get("released") != "" {
+		return
+	}
+	ctx.Put("released", "1")
+	q.done()
+}
+
+func (q *requestQueue) done() {
+	for {
+		q.Lock()
+		q.inFlight--
+		if len(q.pending) == 0 {
+			q.Unlock()
+			return
+		}
+		request := q.pending[0]
+		q.pending = q.pending[1:]
+		q.inFlight++
+		q.Unlock()
+
+		if err := request(); err == nil {
+			return
+		}
+	}
+}
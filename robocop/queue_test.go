@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDedupeHead(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="/about">About</a>
+			<a href="http://other.test/fine">Fine</a>`),
+		// We've crawled the home page by the time we get here.
+		"http://example.com/about": html(`
+			<iframe src="/"></iframe>
+			<a href="http://other.test/fine">Fine</a>`),
+		"http://other.test/fine": {status: 200},
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		requests map[string]int
+	}{
+		{
+			name: "by default",
+			requests: map[string]int{
+				"GET http://example.com/":     1,
+				"HEAD http://example.com/":    1,
+				"HEAD http://other.test/fine": 1,
+			},
+		},
+		{
+			name: "with -dedupe-head",
+			args: []string{"-dedupe-head"},
+			requests: map[string]int{
+				"GET http://example.com/":     1,
+				"HEAD http://example.com/":    0,
+				"HEAD http://other.test/fine": 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.requests = map[string]int{}
+			testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			for request, want := range tt.requests {
+				if got := site.requests[request]; got != want {
+					t.Errorf("got %d of %v, want %d", got, request, want)
+				}
+			}
+			if got := site.count("GET", "http://other.test/fine"); got != 0 {
+				t.Errorf("got %d GETs of http://other.test/fine, want none", got)
+			}
+		})
+	}
+}
+
+// concurrencySite answers from site, but holds on to each request for a
+// little while, and keeps track of the most it had at once.
+type concurrencySite struct {
+	sync.Mutex
+	site     *stubSite
+	inFlight int
+	peak     int
+}
+
+func (s *concurrencySite) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.Lock()
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+	resp, err := s.site.RoundTrip(req)
+
+	s.Lock()
+	s.inFlight--
+	s.Unlock()
+	return resp, err
+}
+
+func TestMaxQueue(t *testing.T) {
+	// A home page linking to 50 sections, each linking to 10 pages of
+	// their own and to a page on another site.
+	pages := map[string]stubPage{}
+	var home strings.Builder
+	for i := 0; i < 50; i++ {
+		section := fmt.Sprintf("http://example.com/%d/", i)
+		fmt.Fprintf(&home, `<a href="%s">Section</a>`, section)
+		var links strings.Builder
+		for j := 0; j < 10; j++ {
+			fmt.Fprintf(&links, `<a href="%s%d">Page</a>`, section, j)
+			pages[fmt.Sprintf("%s%d", section, j)] = html("")
+		}
+		fmt.Fprintf(&links, `<a href="http://other%d.test/">Other</a>`, i)
+		pages[section] = html(links.String())
+		pages[fmt.Sprintf("http://other%d.test/", i)] = stubPage{status: 200}
+	}
+	pages["http://example.com/"] = html(home.String())
+
+	tests := []struct {
+		name     string
+		maxQueue int
+	}{
+		{name: "a queue of 1", maxQueue: 1},
+		{name: "a queue of 4", maxQueue: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &concurrencySite{site: newStubSite(pages)}
+			// Without a limit rule of its own colly would send as many
+			// requests at once as it had.
+			opts := testOptions(t, "-limit-rule=*=100", fmt.Sprintf("-max-queue=%d", tt.maxQueue))
+			_, rows := testCrawl(t, site, "http://example.com/", opts)
+			if len(rows) != 0 {
+				t.Errorf("got report %v, want nothing broken", reported(rows))
+			}
+			if site.peak > tt.maxQueue {
+				t.Errorf("got %d requests at once, want at most %d", site.peak, tt.maxQueue)
+			}
+			if got := len(site.site.requests); got != 1+50*12 {
+				t.Errorf("got %d requests, want one for each page", got)
+			}
+		})
+	}
+
+	// Without -max-queue, the same site gets far more at once.
+	site := &concurrencySite{site: newStubSite(pages)}
+	testCrawl(t, site, "http://example.com/", testOptions(t, "-limit-rule=*=100"))
+	if site.peak <= 4 {
+		t.Errorf("got %d requests at once without -max-queue, want more than the queues above", site.peak)
+	}
+}
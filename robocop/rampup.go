@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The gap between requests when a -ramp-up starts.
+const rampUpStartGap = time.Second
+
+// rampUpTransport eases into a crawl, so that a sudden burst of requests
+// doesn't set off a server's anomaly detection. When the ramp up starts
+// requests are spaced out by rampUpStartGap. The gap shrinks linearly until
+// it's gone at the end of the ramp up, and from then on only the usual
+// delays apply.
+type rampUpTransport struct {
+	transport http.RoundTripper
+	start     time.Time
+	duration  time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRampUpTransport(transport http.RoundTripper, duration time.Duration) *rampUpTransport {
+	return &rampUpTransport{
+		transport: transport,
+		start:     time.Now(),
+		duration:  duration,
+	}
+}
+
+// gap returns how far apart requests should be at now.
+func (t *rampUpTransport) gap(now time.Time) time.Duration {
+	remaining := t.duration - now.Sub(t.start)
+	if remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(rampUpStartGap) * float64(remaining) / float64(t.duration))
+}
+
+func (t *rampUpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	now := time.Now()
+	gap := t.gap(now)
+	if gap == 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	t.mu.Lock()
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(gap)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-timer.C:
+	}
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRampUpGap(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{elapsed: 0, want: rampUpStartGap},
+		{elapsed: 30 * time.Second, want: rampUpStartGap * 3 / 4},
+		{elapsed: 60 * time.Second, want: rampUpStartGap / 2},
+		{elapsed: 120 * time.Second, want: 0},
+		{elapsed: time.Hour, want: 0},
+	}
+
+	r := newRampUpTransport(nil, 2*time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.elapsed.String(), func(t *testing.T) {
+			if got := r.gap(r.start.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("got a gap of %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRampUpRoundTrip(t *testing.T) {
+	site := newStubSite(map[string]stubPage{"http://example.com/": {status: 200}})
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		cancel   bool
+		wantErr  error
+	}{
+		{name: "ramping up", duration: time.Hour},
+		{name: "ramped up", duration: time.Nanosecond},
+		{name: "cancelled while waiting", duration: time.Hour, cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRampUpTransport(site, tt.duration)
+			// The first request goes straight out, and the second waits
+			// for the gap.
+			for i := 0; i < 2; i++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				if tt.cancel && i == 1 {
+					time.AfterFunc(10*time.Millisecond, cancel)
+				}
+				req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+				begin := time.Now()
+				resp, err := r.RoundTrip(req)
+				elapsed := time.Since(begin)
+				cancel()
+
+				if i == 0 {
+					if err != nil {
+						t.Fatal(err)
+					}
+					resp.Body.Close()
+					continue
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				if err == nil {
+					resp.Body.Close()
+				}
+				slow := elapsed > rampUpStartGap/2
+				if want := tt.duration == time.Hour && !tt.cancel; slow != want {
+					t.Errorf("the second request took %v", elapsed)
+				}
+			}
+		})
+	}
+}
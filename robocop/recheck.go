@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// unansweredStatuses are the statuses we report for requests which never
+// got a response, which -recheck tries again along with 4xx and 5xx.
+var unansweredStatuses = map[string]bool{
+	"connection-reset":   true,
+	"dns-error":          true,
+	"not-in-har":         true,
+	"over-budget":        true,
+	"timeout":            true,
+	"unsupported-scheme": true,
+}
+
+// recheck HEAD-checks the links which failed in an earlier report, saved
+// with -baseline-generate, without crawling anything. Statuses from the
+// other checks, like missing-description, are about pages rather than
+// links, so they're left alone. For each link we write
+// LINK<tab>OLD STATUS<tab>NEW STATUS<tab>fixed or still failing to w. The
+// exit code is non-zero if any are still failing.
+func recheck(path string, w io.Writer, res *results, opts *options) (int, error) {
+	file, err := readBaselineFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	before := map[string]string{}
+	for _, entry := range file.Entries {
+		code, err := strconv.Atoi(entry.Status)
+		if (err == nil && code >= 400) || unansweredStatuses[entry.Status] {
+			before[entry.Link] = entry.Status
+		}
+	}
+	links := make([]string, 0, len(before))
+	for link := range before {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	if err := headLinks(links, res, opts); err != nil {
+		return 0, err
+	}
+
+	code := 0
+	for _, link := range links {
+		_, status := res.linkStatus(link, checkedURL(link, opts))
+		if status == "" {
+			status = "error"
+		}
+		verdict := "fixed"
+		if failsRun(status, statusSeverity(status, opts), opts) {
+			verdict = "still failing"
+			code = 1
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", link, before[link], status, verdict); err != nil {
+			return 0, err
+		}
+	}
+	return code, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecheck(t *testing.T) {
+	server, site := serveSite(t, map[string]stubPage{
+		"/fixed": {status: 200},
+		"/reset": {status: 200},
+		"/ok":    {status: 200},
+		"/page":  {status: 200},
+	})
+	base := server.URL
+
+	row := func(link, status string) []string {
+		return reportRow(base+"/", base+link, status)
+	}
+	rows := linkReport{
+		row("/fixed", "404"),
+		row("/gone", "410"),
+		row("/reset", "connection-reset"),
+		row("/ok", "200"),
+		row("/page", "missing-description"),
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := writeBaseline(path, rows, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(t.TempDir())
+	var out strings.Builder
+	code, err := recheck(path, &out, newResults(), testOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		base + "/fixed\t404\t200\tfixed",
+		base + "/gone\t410\t404\tstill failing",
+		base + "/reset\tconnection-reset\t200\tfixed",
+	}
+	if got := strings.TrimSpace(out.String()); got != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	if code != 1 {
+		t.Errorf("got exit code %d, want 1", code)
+	}
+	for _, link := range []string{"/ok", "/page"} {
+		if n := site.count("HEAD", link); n != 0 {
+			t.Errorf("got %d HEADs of %v, want none", n, link)
+		}
+	}
+}
+
+func TestUnansweredStatuses(t *testing.T) {
+	// Every status classifyError can give is one -recheck should try again.
+	for _, status := range []string{"connection-reset", "dns-error", "not-in-har", "over-budget", "timeout", "unsupported-scheme"} {
+		if !unansweredStatuses[status] {
+			t.Errorf("-recheck doesn't try %v links again", status)
+		}
+	}
+}
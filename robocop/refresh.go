@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+)
+
+// metaRefreshSelector finds <meta http-equiv="refresh"> redirects.
+const metaRefreshSelector = `meta[http-equiv="refresh" i][content]`
+
+// metaRefreshURL returns the target of a meta refresh, e.g. /new from
+// "0; url=/new". It's false for refreshes which just reload the page.
+func metaRefreshURL(content string) (string, bool) {
+	i := strings.IndexAny(content, ";,")
+	if i == -1 {
+		return "", false
+	}
+	target := strings.TrimSpace(content[i+1:])
+	if len(target) < 4 || !strings.EqualFold(target[:3], "url") {
+		return "", false
+	}
+	target = strings.TrimSpace(target[3:])
+	if !strings.HasPrefix(target, "=") {
+		return "", false
+	}
+	target = strings.Trim(strings.TrimSpace(target[1:]), `'"`)
+	return target, target != ""
+}
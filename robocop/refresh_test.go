@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetaRefreshURL(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		ok      bool
+	}{
+		{content: "0; url=/new", want: "/new", ok: true},
+		{content: "0;URL=/new", want: "/new", ok: true},
+		{content: "5, url = 'https://example.com/new' ", want: "https://example.com/new", ok: true},
+		{content: `0; url="/new"`, want: "/new", ok: true},
+		{content: "30"},
+		{content: "0; url="},
+		{content: "0; /new"},
+		{content: "0; urn=/new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			got, ok := metaRefreshURL(tt.content)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %q %v, want %q %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFollowMetaRefresh(t *testing.T) {
+	refresh := func(target string) stubPage {
+		return stubPage{
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><head><meta http-equiv="Refresh" content="0; url=` + target + `"></head><body></body></html>`,
+		}
+	}
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":      html(`<a href="/moved">Moved</a><a href="/hop1">Chain</a>`),
+		"http://example.com/moved": refresh("/gone"),
+		"http://example.com/hop1":  refresh("/hop2"),
+		"http://example.com/hop2":  refresh("/hop3"),
+		"http://example.com/hop3":  refresh("/hop4"),
+		"http://example.com/hop4":  html(""),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "without", want: []string{}},
+		{
+			name: "followed",
+			args: []string{"-follow-meta-refresh"},
+			want: []string{
+				"http://example.com/gone 404",
+				"http://example.com/hop3 meta-refresh-chain",
+				"http://example.com/hop4 meta-refresh-chain",
+			},
+		},
+		{
+			name: "too many",
+			args: []string{"-follow-meta-refresh", "-max-meta-refresh=2"},
+			want: []string{
+				"http://example.com/gone 404",
+				"http://example.com/hop3 meta-refresh-chain",
+				"http://example.com/hop4 too-many-meta-refreshes",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
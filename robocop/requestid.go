@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// requestIDTransport sends a new random ID in header with every request,
+// for -request-id-header, and hands it to record along with the URL, so
+// that the report can say which line of the server's access log to look
+// at. Retries are requests of their own, so they get new IDs.
+type requestIDTransport struct {
+	transport http.RoundTripper
+	header    string
+	record    func(link, id string)
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, err := newRequestID()
+	if err != nil {
+		return nil, err
+	}
+	// A RoundTripper mustn't change the request it's given.
+	clone := req.Clone(req.Context())
+	clone.Header.Set(t.header, id)
+	t.record(req.URL.String(), id)
+	return t.transport.RoundTrip(clone)
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
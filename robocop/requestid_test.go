@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/gone">Gone</a><a href="http://other.test/also-gone">Also gone</a>`),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "without"},
+		{name: "with", args: []string{"-request-id-header=X-Request-ID"}, want: true},
+	}
+
+	id := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := &headerTransport{transport: site, header: "X-Request-ID", values: map[string]string{}}
+			_, rows := testCrawl(t, sent, "http://example.com/", testOptions(t, tt.args...))
+			if len(rows) != 2 {
+				t.Fatalf("got %d rows, want 2", len(rows))
+			}
+
+			seen := map[string]bool{}
+			for _, row := range rows {
+				got := row[requestIDColumn]
+				if want := sent.values[row[linkColumn]]; got != want {
+					t.Errorf("%s: reported ID %q, sent %q", row[linkColumn], got, want)
+				}
+				if !tt.want {
+					if got != "" {
+						t.Errorf("%s: got ID %q, want none", row[linkColumn], got)
+					}
+					continue
+				}
+				if !id.MatchString(got) || seen[got] {
+					t.Errorf("%s: got ID %q, want a new one", row[linkColumn], got)
+				}
+				seen[got] = true
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// retryTransport retries requests which come back with a retryable status,
+// waiting a little longer before each attempt. Connections which were
+// reset, which CDNs do now and then, are retried up to resets times on top
+// of that. Anything else, including a status which isn't retryable, is
+// passed straight back.
+type retryTransport struct {
+	transport http.RoundTripper
+	retries   int
+	// statuses to retry. If empty, every 5xx is retried.
+	statuses statusList
+	resets   int
+	backoff  time.Duration
+}
+
+// isConnectionReset is true for errors which mean the other end hung up on
+// us partway through.
+func isConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func (t *retryTransport) retryable(status int) bool {
+	if len(t.statuses) == 0 {
+		return status >= 500
+	}
+	return t.statuses[status]
+}
+
+// rewind returns req ready to be sent again, or false if its body has
+// been read and there's no getting it back.
+func rewind(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return req, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return req, false
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, true
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retries, resets := 0, 0
+	for {
+		resp, err := t.transport.RoundTrip(req)
+		switch {
+		case err != nil:
+			if !isConnectionReset(err) || resets >= t.resets {
+				return resp, err
+			}
+		case retries >= t.retries || !t.retryable(resp.StatusCode):
+			return resp, err
+		}
+
+		next, ok := rewind(req)
+		if !ok {
+			return resp, err
+		}
+		if err != nil {
+			resets++
+		} else {
+			// Drain the body so that the connection can be reused.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			retries++
+		}
+		req = next
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(retries+resets) * t.backoff):
+		}
+	}
+}
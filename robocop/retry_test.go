@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// flakyTransport answers with each of answers in turn, a status or an
+// error, and then 200 for good.
+type flakyTransport struct {
+	answers  []interface{}
+	requests int
+	// bodies sent with each request.
+	bodies []string
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests++
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		f.bodies = append(f.bodies, string(body))
+	}
+	if f.requests > len(f.answers) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	switch answer := f.answers[f.requests-1].(type) {
+	case int:
+		return &http.Response{StatusCode: answer, Body: io.NopCloser(strings.NewReader(""))}, nil
+	case error:
+		return nil, answer
+	}
+	panic(fmt.Sprintf("can't answer with %v", f.answers[f.requests-1]))
+}
+
+func TestRetryTransport(t *testing.T) {
+	reset := fmt.Errorf("read: %w", syscall.ECONNRESET)
+
+	tests := []struct {
+		name     string
+		retries  int
+		resets   int
+		statuses statusList
+		answers  []interface{}
+		want     int
+		wantErr  error
+		requests int
+	}{
+		{name: "no retries", answers: []interface{}{503}, want: 503, requests: 1},
+		{name: "retried until it works", retries: 3, answers: []interface{}{503, 500}, want: 200, requests: 3},
+		{name: "out of retries", retries: 1, answers: []interface{}{503, 502}, want: 502, requests: 2},
+		{name: "not a status we retry", retries: 3, answers: []interface{}{404}, want: 404, requests: 1},
+		{name: "only the statuses given", retries: 3, statuses: statusList{429: true}, answers: []interface{}{429, 503}, want: 503, requests: 2},
+		{name: "resets", resets: 2, answers: []interface{}{reset, io.ErrUnexpectedEOF}, want: 200, requests: 3},
+		{name: "too many resets", resets: 1, answers: []interface{}{reset, reset}, wantErr: syscall.ECONNRESET, requests: 2},
+		{name: "resets aren't retries", retries: 1, resets: 1, answers: []interface{}{reset, 503}, want: 200, requests: 3},
+		{name: "other errors aren't retried", retries: 3, resets: 3, answers: []interface{}{errors.New("no route")}, wantErr: errors.New("no route"), requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyTransport{answers: tt.answers}
+			transport := &retryTransport{transport: flaky, retries: tt.retries, resets: tt.resets, statuses: tt.statuses}
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			resp, err := transport.RoundTrip(req)
+
+			switch {
+			case tt.wantErr != nil:
+				if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("got error %v, want %d", err, tt.want)
+			case resp.StatusCode != tt.want:
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.want)
+			}
+			if flaky.requests != tt.requests {
+				t.Errorf("made %d requests, want %d", flaky.requests, tt.requests)
+			}
+		})
+	}
+}
+
+// onceReader can only be read the once; http.NewRequest can't give a
+// request with it a GetBody.
+type onceReader struct{ io.Reader }
+
+func TestRetryBody(t *testing.T) {
+	reset := fmt.Errorf("read: %w", syscall.ECONNRESET)
+
+	tests := []struct {
+		name    string
+		body    io.Reader
+		answers []interface{}
+		want    int
+		bodies  []string
+	}{
+		{name: "sent again", body: strings.NewReader("q=1"), answers: []interface{}{503}, want: 200, bodies: []string{"q=1", "q=1"}},
+		{name: "sent again after a reset", body: strings.NewReader("q=1"), answers: []interface{}{reset}, want: 200, bodies: []string{"q=1", "q=1"}},
+		{name: "can't be sent again", body: onceReader{strings.NewReader("q=1")}, answers: []interface{}{503}, want: 503, bodies: []string{"q=1"}},
+		{name: "no body", body: http.NoBody, answers: []interface{}{503}, want: 200, bodies: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyTransport{answers: tt.answers}
+			transport := &retryTransport{transport: flaky, retries: 1, resets: 1}
+			req, _ := http.NewRequest("POST", "http://example.com/", tt.body)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("got error %v, want %d", err, tt.want)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("got %d, want %d", resp.StatusCode, tt.want)
+			}
+			if strings.Join(flaky.bodies, " ") != strings.Join(tt.bodies, " ") || len(flaky.bodies) != len(tt.bodies) {
+				t.Errorf("sent bodies %q, want %q", flaky.bodies, tt.bodies)
+			}
+		})
+	}
+}
@@ -1,43 +1,587 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/gocolly/colly"
 	"github.com/olekukonko/tablewriter"
 )
 
-// usage: go run robocop.go -verbose -host=https://example.com
+// usage: go run . -verbose -host=https://example.com
+
+// linkSelector matches the elements whose href we crawl and check. <area>
+// is for the links in image maps.
+const linkSelector = "a[href], area[href]"
+
+// embedSelector matches embedded content. We check these, but they aren't
+// links, so we don't crawl them.
+const embedSelector = "iframe[src], embed[src], object[data]"
+
+// urlAttr returns the attribute which holds the URL for an element.
+func urlAttr(element string) string {
+	switch element {
+	case "a", "area", "link":
+		return "href"
+	case "object":
+		return "data"
+	}
+	return "src"
+}
 
 type linkReport [][]string
 type headReport = map[string]int
 type pageReport = map[string]map[string]string
+type redirectReport = map[string]string
+type sizeReport = map[string]int
+type errorReport = map[string]string
+type methodReport = map[string]string
+
+// options holds the settings we get from the command line.
+type options struct {
+	all                   bool
+	allowInsecureHosts    stringList
+	assumeOKHosts         stringList
+	baseline              baseline
+	baselineAccept        bool
+	baselineGenerate      string
+	baseURL               string
+	bearerToken           string
+	charset               string
+	checkAMP              bool
+	checkDescriptions     bool
+	checkFavicon          bool
+	checkFeeds            bool
+	checkForms            bool
+	checkJSONLD           bool
+	checkMailto           bool
+	checkMismatchedAnchor bool
+	checkPDF              bool
+	checkResourceHints    bool
+	checks                checkList
+	checkSRI              bool
+	checkStdin            bool
+	checkTextLinks        bool
+	checkWWW              bool
+	color                 string
+	connectionWarmup      time.Duration
+	countOnly             bool
+	coverage              bool
+	crawlWindow           crawlWindow
+	csv                   bool
+	csvStream             string
+	dedupeHead            bool
+	detectCycles          bool
+	emitSitemap           string
+	excludeFromExit       stringSet
+	externalBatchInterval time.Duration
+	externalBatchSize     int
+	extraSelectors        extraSelectors
+	failOn                statusList
+	file                  string
+	flagPrivateHosts      bool
+	flat                  bool
+	followMetaRefresh     bool
+	followPagination      bool
+	followRedirects       bool
+	githubAnnotations     bool
+	groupByStatus         bool
+	harFile               string
+	headFallback          statusList
+	headThenRange         bool
+	hostTimeouts          hostTimeouts
+	hosts                 stringList
+	ignore                ignoreList
+	ignoreFile            string
+	ignoreFragments       bool
+	isolateHosts          bool
+	limitRules            limitRules
+	listReferrers         bool
+	loginFields           formFields
+	loginSuccessSelector  string
+	loginURL              string
+	maxBodySize           int
+	maxConsecutiveErrors  int
+	maxDelay              time.Duration
+	maxErrors             int
+	maxMetaRefresh        int
+	maxPagesPerHost       int
+	maxQueue              int
+	maxResponseTime       time.Duration
+	maxURLWidth           int
+	maxVisits             int
+	minDelay              time.Duration
+	minHealth             float64
+	noExternal            bool
+	normalizeUnicode      bool
+	onlyFailures          bool
+	outputTimestamp       bool
+	pageSummary           bool
+	pprofAddr             string
+	rampUp                time.Duration
+	randomDelay           int
+	recheck               string
+	reportDuplicateLinks  bool
+	reportSelfLinks       bool
+	reportTypes           stringSet
+	requestBudget         int
+	requestIDHeader       string
+	requireAMPHTML        bool
+	resetRetries          int
+	respectRobots         bool
+	retries               int
+	retryStatus           statusList
+	routesFile            string
+	schemes               stringSet
+	sortQuery             bool
+	sqlitePath            string
+	statusPriority        stringList
+	stdin                 bool
+	strictTLS             bool
+	summary               bool
+	textfile              string
+	timeout               time.Duration
+	tlsExpiryWindow       time.Duration
+	tokenRefreshCmd       string
+	treatHTTPAsFailure    bool
+	unixSocket            string
+	validateCmd           string
+	validateParallel      int
+	validateSitemap       string
+	validateTimeout       time.Duration
+	verbose               bool
+	verboseJSON           string
+	warmCache             bool
+	warningsAsErrors      bool
+	warnStatus            statusList
+}
+
+// results holds everything we learn during a crawl. Callbacks run
+// concurrently, so lock it before touching the maps.
+type results struct {
+	sync.Mutex
+	assumedOK      map[string]bool
+	breaker        *circuitBreaker
+	budget         *requestBudget
+	canonicalHosts map[string]string
+	checks         map[string]Check
+	depths         map[string]int
+	errors         errorReport
+	events         *eventLog
+	finalURLs      map[string]string
+	findings       []Finding
+	heads          headReport
+	hostVisits     map[string]int
+	invalid        map[string]string
+	lastModified   map[string]string
+	linkCounts     map[string]map[string]int
+	linkIndex      map[string][][2]string
+	methods        methodReport
+	pages          pageReport
+	rangeFailed    map[string]bool
+	redirects      redirectReport
+	refreshes      map[string]int
+	requestIDs     map[string]string
+	sizes          sizeReport
+	skipped        map[string]bool
+	stream         *csvStream
+	tooSlow        map[string]bool
+}
+
+func newResults() *results {
+	return &results{
+		assumedOK:      map[string]bool{},
+		canonicalHosts: map[string]string{},
+		depths:         map[string]int{},
+		errors:         errorReport{},
+		finalURLs:      map[string]string{},
+		heads:          headReport{},
+		hostVisits:     map[string]int{},
+		invalid:        map[string]string{},
+		lastModified:   map[string]string{},
+		linkCounts:     map[string]map[string]int{},
+		linkIndex:      map[string][][2]string{},
+		methods:        methodReport{},
+		pages:          pageReport{},
+		rangeFailed:    map[string]bool{},
+		redirects:      redirectReport{},
+		refreshes:      map[string]int{},
+		requestIDs:     map[string]string{},
+		sizes:          sizeReport{},
+		skipped:        map[string]bool{},
+		tooSlow:        map[string]bool{},
+	}
+}
+
+// addLink records that link was found on the page source, in the given
+// element, e.g. "a" or "iframe". pages only has each link once, so
+// linkCounts keeps track of how many times we found it.
+func (res *results) addLink(source, link, element string) {
+	res.Lock()
+	defer res.Unlock()
+
+	if _, ok := res.pages[source]; !ok {
+		res.pages[source] = map[string]string{}
+		res.linkCounts[source] = map[string]int{}
+	}
+	if _, ok := res.pages[source][link]; !ok && res.stream != nil {
+		key := withoutFragment(link)
+		res.linkIndex[key] = append(res.linkIndex[key], [2]string{source, link})
+	}
+	res.pages[source][link] = element
+	res.linkCounts[source][link]++
+}
+
+// isCanonical is true if host is the canonical form of one of the hosts we
+// were asked to crawl, e.g. www.example.com when example.com redirects
+// there.
+func (res *results) isCanonical(host string) bool {
+	res.Lock()
+	defer res.Unlock()
+	for _, canonical := range res.canonicalHosts {
+		if canonical == host {
+			return true
+		}
+	}
+	return false
+}
+
+// foundAt records the crawl depth we first found page at. The seeds are
+// at depth 0, the pages they link to at 1 and so on. The caller must hold
+// the lock.
+func (res *results) foundAt(page string, depth int) {
+	if _, ok := res.depths[page]; !ok {
+		res.depths[page] = depth
+	}
+}
+
+// finalURL returns where link, which was requested as checked, ended up
+// once colly had followed any redirects. That's the link itself if there
+// weren't any, and nothing if we never got a response.
+func (res *results) finalURL(link, checked string) string {
+	if final, ok := res.finalURLs[checked]; ok {
+		return final
+	}
+	if res.heads[checked] == 0 {
+		return ""
+	}
+	return link
+}
+
+// linkStatus returns the status code for link, which was requested as
+// checked, and the status we report for it. Requests which never got a
+// response have no status code, so the status says what went wrong
+// instead, where we know. Links which worked but were over
+// -max-response-time are too-slow, ones whose body we couldn't GET after a
+// HEAD are range-failed, and -validate-cmd has the last word.
+func (res *results) linkStatus(link, checked string) (int, string) {
+	code := res.heads[checked]
+	if status := res.invalid[checked]; status != "" {
+		return code, status
+	}
+	if code != 0 && code < 400 && res.tooSlow[checked] {
+		return code, "too-slow"
+	}
+	if code != 0 && res.rangeFailed[checked] {
+		return code, "range-failed"
+	}
+	if code != 0 {
+		return code, strconv.Itoa(code)
+	}
+	if res.skipped[link] {
+		return code, "skipped"
+	}
+	if res.assumedOK[link] {
+		return code, "assumed-ok"
+	}
+	return code, res.errors[checked]
+}
+
+// screenLink decides whether a link found on source, once it's been
+// normalized, is worth checking. It isn't if it's ignored, external with
+// -no-external or on one of -assume-ok-hosts, and the assumed ones are
+// recorded as such. With -flag-private-hosts, a link to a private host is
+// reported but still checked. inScope is the hosts we're crawling, and
+// private is nil unless we're flagging private hosts.
+func (res *results) screenLink(opts *options, inScope map[string]bool, private *privateHosts, source string, link *url.URL, element string) bool {
+	if pattern, ok := opts.ignore.ignores(link.String()); ok {
+		if opts.verbose {
+			log.Printf("Ignoring %v because it matches %v", link.String(), pattern)
+		}
+		return false
+	}
+
+	if opts.noExternal && !inScope[link.Host] && !res.isCanonical(link.Host) {
+		return false
+	}
+
+	if isAssumedOK(link.Host, opts.assumeOKHosts) {
+		res.addLink(source, link.String(), element)
+		res.Lock()
+		res.assumedOK[link.String()] = true
+		res.Unlock()
+		return false
+	}
+
+	// The hosts we're crawling can be private, e.g. a staging server,
+	// so it's only links elsewhere which count.
+	if private != nil && !inScope[link.Host] && private.isPrivate(link.Hostname()) {
+		res.Lock()
+		res.findings = append(res.findings, Finding{
+			Page:   source,
+			Link:   link.String(),
+			Status: "private-host",
+		})
+		res.Unlock()
+	}
+	return true
+}
+
+// skipLink records a link found on source which we aren't going to check.
+func (res *results) skipLink(source, link, element string) {
+	res.addLink(source, link, element)
+
+	res.Lock()
+	res.skipped[link] = true
+	res.Unlock()
+}
+
+// defineFlags sets opts to its defaults and registers the command line
+// flags which fill it in on fs.
+func defineFlags(fs *flag.FlagSet, opts *options) {
+	*opts = options{
+		baseline:        baseline{},
+		checks:          checkList{brokenCheck: true},
+		excludeFromExit: stringSet{},
+		headFallback:    statusList{405: true, 501: true},
+		loginFields:     formFields{},
+		reportTypes:     stringSet{},
+		retryStatus:     statusList{},
+		failOn:          statusList{},
+		warnStatus:      statusList{},
+		schemes:         stringSet{"http": true, "https": true},
+	}
+
+	fs.StringVar(&opts.pprofAddr, "pprof-addr", "", "serve net/http/pprof on this address, e.g. localhost:6060, while crawling")
+	fs.IntVar(&opts.randomDelay, "random-delay", 1, "random delay (in seconds)")
+	fs.IntVar(&opts.maxVisits, "max-visits", 10000, "maximum number of pages to scrape across all hosts")
+	fs.BoolVar(&opts.all, "all", false, "show every link, including successful ones")
+	fs.StringVar(&opts.charset, "charset", "", "decode every page with this charset rather than detecting it")
+	fs.Var(opts.checks, "checks", "comma separated checks to run, from "+strings.Join(checkNames(), ","))
+	fs.StringVar(&opts.color, "color", "auto", "colorize statuses in the report: auto, always or never")
+	fs.BoolVar(&opts.csv, "csv", false, "dump data in CSV format")
+	fs.BoolVar(&opts.followPagination, "follow-pagination", false, "crawl pages linked with <link rel=\"next\"> and <link rel=\"prev\">")
+	fs.BoolVar(&opts.followRedirects, "follow-redirects", true, "follow redirects rather than reporting the 3xx status and Location")
+	fs.BoolVar(&opts.listReferrers, "list-referrers", false, "report each broken link once, listing every page it was found on")
+	fs.BoolVar(&opts.onlyFailures, "only-failures", false, "show only failures")
+	fs.BoolVar(&opts.reportSelfLinks, "report-self-links", false, "report links from a page to itself (same as adding self to -checks)")
+	fs.BoolVar(&opts.respectRobots, "respect-meta-robots", false, "don't follow links on pages with a nofollow robots meta tag or X-Robots-Tag header")
+	fs.BoolVar(&opts.sortQuery, "sort-query", false, "sort query parameters so reordered but equivalent URLs are only visited once")
+	fs.BoolVar(&opts.summary, "summary", false, "print a summary of the crawl after the report")
+	fs.DurationVar(&opts.timeout, "timeout", 10*time.Second, "request timeout")
+	fs.Var(opts.headFallback, "head-method-fallback", "retry links with GET when a HEAD returns one of these statuses (empty to disable)")
+	fs.Var(&opts.hostTimeouts, "host-timeout", "request timeout for matching hosts as pattern=duration, e.g. *.example.com=30s (repeatable)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "turn on verbose mode")
+	fs.Var(&opts.hosts, "host", "host to crawl (repeatable)")
+	fs.IntVar(&opts.maxPagesPerHost, "max-pages-per-host", 0, "maximum number of pages to scrape on each host (0 for no limit)")
+	fs.StringVar(&opts.file, "file", "", "check the links in a local HTML file rather than crawling a host")
+	fs.BoolVar(&opts.stdin, "stdin", false, "check the links in HTML read from standard input rather than crawling a host")
+	fs.StringVar(&opts.baseURL, "base-url", "", "resolve relative links in -file or -stdin against this URL")
+	fs.StringVar(&opts.sqlitePath, "sqlite", "", "write pages, links and statuses to this SQLite database")
+	fs.StringVar(&opts.emitSitemap, "emit-sitemap", "", "write a sitemap.xml of the crawled 2xx pages to this file")
+	fs.Var(opts.schemes, "schemes", "link schemes to check, links with any other scheme are skipped")
+	fs.BoolVar(&opts.checkMailto, "check-mailto", false, "report mailto links with malformed addresses (same as adding mailto to -checks)")
+	fs.DurationVar(&opts.minDelay, "min-delay", 0, "minimum delay between requests to a host, overrides -random-delay")
+	fs.DurationVar(&opts.maxDelay, "max-delay", 0, "maximum delay between requests to a host, overrides -random-delay")
+	fs.BoolVar(&opts.warningsAsErrors, "warnings-as-errors", false, "exit non-zero for warnings, like 3xx statuses, as well as errors")
+	fs.IntVar(&opts.maxQueue, "max-queue", 0, "maximum number of requests to have queued up at once (0 for no limit)")
+	fs.Float64Var(&opts.minHealth, "min-health", 0, "exit non-zero if fewer than this percentage of links are 2xx, rather than on any error")
+	fs.StringVar(&opts.unixSocket, "unix-socket", "", "path to a Unix socket to send requests for the crawled hosts to")
+	fs.IntVar(&opts.retries, "retries", 0, "number of times to retry a request which comes back with a retryable status")
+	fs.Var(opts.retryStatus, "retry-status", "comma separated status codes to retry (default every 5xx)")
+	fs.IntVar(&opts.resetRetries, "reset-retries", 0, "number of times to retry a request whose connection was reset or closed early, on top of -retries")
+	fs.BoolVar(&opts.flat, "flat", false, "print the report as sorted STATUS<tab>SOURCE<tab>LINK lines instead of a table")
+	fs.StringVar(&opts.loginURL, "login-url", "", "URL to POST a login form to before crawling")
+	fs.Var(opts.loginFields, "login-fields", "key=value field to send to -login-url (can be repeated)")
+	fs.StringVar(&opts.loginSuccessSelector, "login-success-selector", "", "CSS selector which is only on the page after a successful login")
+	fs.BoolVar(&opts.ignoreFragments, "ignore-fragments", true, "check links which only differ by #fragment once")
+	fs.StringVar(&opts.csvStream, "csv-stream", "", "path to write failures to as CSV as soon as they are found")
+	fs.BoolVar(&opts.checkDescriptions, "check-descriptions", false, "report pages with missing or duplicate meta descriptions (same as adding descriptions to -checks)")
+	fs.BoolVar(&opts.checkStdin, "check-stdin", false, "HEAD check the URLs on stdin, one per line, and print URL<tab>STATUS for each, without crawling")
+	fs.Var(&opts.limitRules, "limit-rule", "parallelism and optional delay for hosts matching a glob, e.g. *.cdn.com=8/100ms (can be repeated)")
+	fs.BoolVar(&opts.treatHTTPAsFailure, "treat-http-as-failure", false, "report every http link as an error, even if it works")
+	fs.Var(opts.excludeFromExit, "exclude-status-from-exit", "comma separated statuses, e.g. 404,dns-error, which are reported but don't make us exit non-zero")
+	fs.DurationVar(&opts.rampUp, "ramp-up", 0, "start by spacing requests a second apart and speed up to full rate over this long, e.g. 2m")
+	fs.BoolVar(&opts.checkJSONLD, "check-jsonld", false, "HEAD check the URLs in JSON-LD structured data")
+	fs.Var(opts.baseline, "baseline", "path to a baseline from -baseline-generate; accepted failures in it are left out of the report")
+	fs.StringVar(&opts.baselineGenerate, "baseline-generate", "", "path to save the report to as a baseline")
+	fs.BoolVar(&opts.baselineAccept, "baseline-accept", true, "mark the failures saved by -baseline-generate as accepted")
+	fs.DurationVar(&opts.maxResponseTime, "max-response-time", 0, "report links which take longer than this to respond as too-slow, e.g. 2s")
+	fs.BoolVar(&opts.groupByStatus, "group-by-status", false, "print a table per status, worst first, instead of one table")
+	fs.BoolVar(&opts.checkWWW, "check-www", false, "report internal links to the www or non-www host which the site redirects away from")
+	fs.Var(&opts.extraSelectors, "extra-selectors", "custom links to check as selector@attr, e.g. [data-href]@data-href (can be repeated)")
+	fs.IntVar(&opts.requestBudget, "request-budget", 0, "maximum number of HTTP requests to make over the whole run (0 for no limit)")
+	fs.BoolVar(&opts.checkPDF, "check-pdf", false, "download linked PDFs and check the links inside them")
+	fs.IntVar(&opts.maxBodySize, "max-body-size", 10*1024*1024, "largest response body, in bytes, we will read, including PDFs for -check-pdf")
+	fs.BoolVar(&opts.normalizeUnicode, "normalize-unicode", false, "convert international domain names to punycode and normalize percent-encoding in paths, so equivalent URLs are only visited once")
+	fs.StringVar(&opts.harFile, "har", "", "replay the responses recorded in this HAR file instead of making live requests")
+	fs.StringVar(&opts.validateCmd, "validate-cmd", "", "command to run for each checked link, with the URL and status as arguments; a non-zero exit marks the link invalid, with the first line of output as its status")
+	fs.IntVar(&opts.validateParallel, "validate-parallel", 4, "number of -validate-cmd commands to run at once")
+	fs.DurationVar(&opts.validateTimeout, "validate-timeout", 10*time.Second, "how long to give each -validate-cmd command")
+	fs.BoolVar(&opts.checkAMP, "check-amp", false, "check that the AMP versions pages link to work and link back (same as adding amp to -checks)")
+	fs.BoolVar(&opts.requireAMPHTML, "require-amphtml", false, "report the pages which don't link to an AMP version as missing-amphtml (same as adding amp-required to -checks)")
+	fs.Var(opts.reportTypes, "report-types", "only report links found in these elements, e.g. a,iframe")
+	fs.BoolVar(&opts.strictTLS, "strict-tls", false, "report https hosts with certificates expiring within -tls-expiry-window, self-signed or untrusted certificates, or TLS older than 1.2")
+	fs.DurationVar(&opts.tlsExpiryWindow, "tls-expiry-window", 30*24*time.Hour, "with -strict-tls, report certificates which expire within this long")
+	fs.BoolVar(&opts.pageSummary, "page-summary", false, "print the number of broken links on each page, worst first, after the report")
+	fs.StringVar(&opts.routesFile, "routes", "", "file of single page app routes, one per line, to crawl as well as the hosts, e.g. /app/dashboard")
+	fs.BoolVar(&opts.checkMismatchedAnchor, "check-mismatched-anchor", false, "report links whose text is a URL other than the one they link to (same as adding mismatched-anchor to -checks)")
+	fs.IntVar(&opts.externalBatchSize, "external-batch-size", 0, "HEAD external links in batches of this many, rather than as soon as we find them")
+	fs.DurationVar(&opts.externalBatchInterval, "external-batch-interval", 0, "HEAD any external links waiting for a batch this often, e.g. 5s")
+	fs.BoolVar(&opts.followMetaRefresh, "follow-meta-refresh", false, "follow <meta http-equiv=\"refresh\"> redirects and report chains of them")
+	fs.IntVar(&opts.maxMetaRefresh, "max-meta-refresh", 10, "with -follow-meta-refresh, stop following a chain of meta refreshes after this many")
+	fs.StringVar(&opts.textfile, "textfile", "", "write the crawl's metrics to this file for node_exporter's textfile collector, e.g. robocop.prom")
+	fs.StringVar(&opts.recheck, "recheck", "", "HEAD check just the links which failed in this report, saved with -baseline-generate, and say which are fixed")
+	fs.IntVar(&opts.maxErrors, "max-errors", 0, "stop crawling and report what we have once more than this many requests have failed, with no response or a 5xx")
+	fs.IntVar(&opts.maxConsecutiveErrors, "max-consecutive-errors", 0, "stop crawling and report what we have once more than this many requests in a row have failed")
+	fs.BoolVar(&opts.checkResourceHints, "check-resource-hints", false, "HEAD check the preload and prefetch URLs in Link response headers")
+	fs.StringVar(&opts.verboseJSON, "verbose-json", "", "write a JSON object per line to this file, or - for standard error, for each request, response, link found and failure")
+	fs.Var(&opts.allowInsecureHosts, "allow-insecure-hosts", "skip TLS certificate verification for these hosts only, e.g. staging.internal (repeatable)")
+	fs.BoolVar(&opts.checkFeeds, "check-feeds", false, "crawl the RSS and Atom feeds pages link to, and HEAD check the link of every item in them")
+	fs.BoolVar(&opts.headThenRange, "head-then-range", false, "after a 2xx HEAD of anything other than HTML, GET its first byte and report it as range-failed if that fails")
+	fs.BoolVar(&opts.detectCycles, "detect-cycles", false, "print the groups of crawled pages which link to each other in a loop, after the report")
+	fs.StringVar(&opts.bearerToken, "bearer-token", "", "send this token in an Authorization: Bearer header to the hosts we crawl")
+	fs.StringVar(&opts.tokenRefreshCmd, "token-refresh-cmd", "", "command which prints a new bearer token, run when a request to a host we crawl comes back 401")
+	fs.BoolVar(&opts.dedupeHead, "dedupe-head", false, "don't HEAD links we already have a 2xx for, e.g. pages we crawled or the targets of redirects")
+	fs.BoolVar(&opts.coverage, "coverage", false, "print how many of the links on each page were checked, skipped or never reached, after the report")
+	fs.Var(&opts.statusPriority, "status-priority", "comma separated statuses, e.g. 500,404,dns-error, to put first in the report, in that order")
+	fs.BoolVar(&opts.noExternal, "no-external", false, "ignore links to other hosts entirely: don't check them or report them")
+	fs.BoolVar(&opts.flagPrivateHosts, "flag-private-hosts", false, "report links to hosts which resolve to loopback, link-local or private addresses, like localhost, as private-host")
+	fs.BoolVar(&opts.isolateHosts, "isolate-hosts", false, "crawl each -host at the same time with its own collector, and its own -max-visits, then merge the reports")
+	fs.StringVar(&opts.requestIDHeader, "request-id-header", "", "send a unique ID in this header, e.g. X-Request-ID, with every request, and show it in the report")
+	fs.BoolVar(&opts.checkFavicon, "check-favicon", false, "check the icons and apple-touch-icons pages declare, or /favicon.ico for pages which don't declare one")
+	fs.IntVar(&opts.maxURLWidth, "max-url-width", 0, "cut URLs longer than this short in the report table; CSV and other output keep them whole")
+	fs.Var(&opts.assumeOKHosts, "assume-ok-hosts", "don't check links to these hosts or their subdomains, e.g. twitter.com, and report them as assumed-ok (repeatable)")
+	fs.StringVar(&opts.validateSitemap, "validate-sitemap", "", "report the entries in this sitemap, a file or URL, whose lastmod is malformed or in the future")
+	fs.BoolVar(&opts.githubAnnotations, "github-annotations", false, "also print an ::error:: or ::warning:: GitHub Actions workflow command for each failure")
+	fs.Var(&opts.crawlWindow, "crawl-window", "only make requests between these local times, e.g. 22:00-06:00, and pause outside them")
+	fs.BoolVar(&opts.checkForms, "check-forms", false, "check where forms submit to, and report forms on https pages which submit over http as insecure-form (same as adding forms to -checks)")
+	fs.BoolVar(&opts.warmCache, "warm-cache", false, "crawl to fill .url-cache and exit without a report, so that a later run is quicker")
+	fs.BoolVar(&opts.checkTextLinks, "check-text-links", false, "HEAD check the links in the Markdown and text files we come across, e.g. README.md")
+	fs.BoolVar(&opts.outputTimestamp, "output-timestamp", false, "start the CSV report with # comment lines, and give the -baseline-generate file a meta object, saying when the run started, how long it took, what it checked and how many pages it fetched")
+	fs.DurationVar(&opts.connectionWarmup, "connection-warmup", 0, "send the first requests to each host in waves this far apart, e.g. 200ms, 1 then 2 then 4 and so on, so that their connections aren't all opened at once")
+	fs.BoolVar(&opts.checkSRI, "check-sri", false, "download the scripts and stylesheets with an integrity attribute, and report the ones whose sha256, sha384 or sha512 hash doesn't match as sri-mismatch")
+	fs.BoolVar(&opts.countOnly, "count-only", false, "print nothing but the number of links which fail the run, instead of the report, e.g. for FAILS=$(robocop -count-only ...)")
+	fs.StringVar(&opts.ignoreFile, "ignore-file", "", "file of URL patterns, one per line with * and ? globs, whose links are neither checked nor reported (default .linkauditorignore, if there is one)")
+	fs.BoolVar(&opts.reportDuplicateLinks, "report-duplicate-links", false, "print the links which pages link to more than once, and how many times, after the report")
+	fs.Var(opts.warnStatus, "warn-status", "comma separated status codes, e.g. 204,206, to report as warnings, even though they're 2xx")
+	fs.Var(opts.failOn, "fail-on", "comma separated status codes, e.g. 204, to report as errors, even though they're 2xx")
+}
+
+// enableChecks turns on the checks which have a flag of their own, like
+// -check-mailto, as though they'd been given to -checks.
+func enableChecks(opts *options) {
+	if opts.checkMailto {
+		opts.checks["mailto"] = true
+	}
+	if opts.reportSelfLinks {
+		opts.checks["self"] = true
+	}
+	if opts.checkDescriptions {
+		opts.checks["descriptions"] = true
+	}
+	if opts.checkAMP {
+		opts.checks["amp"] = true
+	}
+	if opts.requireAMPHTML {
+		opts.checks["amp-required"] = true
+	}
+	if opts.checkForms {
+		opts.checks["forms"] = true
+	}
+	if opts.checkMismatchedAnchor {
+		opts.checks["mismatched-anchor"] = true
+	}
+}
 
 func main() {
-	var maxVisits, randomDelay int
-	var csv, onlyFailures, verbose bool
-	var host string
-
-	flag.IntVar(&randomDelay, "random-delay", 1, "random delay (in seconds)")
-	flag.IntVar(&maxVisits, "max-visits", 10000, "maximum number of pages to scrape")
-	flag.BoolVar(&csv, "csv", false, "dump data in CSV format")
-	flag.BoolVar(&onlyFailures, "only-failures", false, "show only failures")
-	flag.BoolVar(&verbose, "verbose", false, "turn on verbose mode")
-	flag.StringVar(&host, "host", "", "host to crawl")
+	var opts options
+	defineFlags(flag.CommandLine, &opts)
 	flag.Parse()
+	started := time.Now()
+
+	color, err := useColor(opts.color, os.Stdout)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	enableChecks(&opts)
 
-	var heads = map[string]int{}
-	var pages = map[string]map[string]string{}
+	if err := checkDelays(&opts); err != nil {
+		log.Fatalln(err)
+	}
+
+	if opts.all && opts.onlyFailures {
+		log.Fatalln("-all and -only-failures cannot be used together")
+	}
+	if opts.warmCache && opts.harFile != "" {
+		log.Fatalln("-warm-cache and -har cannot be used together, since -har doesn't use the cache")
+	}
+	if opts.baselineGenerate != "" && len(opts.baseline) > 0 {
+		log.Fatalln("-baseline-generate and -baseline cannot be used together, since the new baseline would leave out the rows -baseline accepts")
+	}
+
+	ignoreFile := opts.ignoreFile
+	if ignoreFile == "" {
+		if _, err := os.Stat(defaultIgnoreFile); err == nil {
+			ignoreFile = defaultIgnoreFile
+		}
+	}
+	if ignoreFile != "" {
+		opts.ignore, err = readIgnoreFile(ignoreFile)
+		if err != nil {
+			log.Fatalf("cannot read %s because %v", ignoreFile, err)
+		}
+	}
+
+	res := newResults()
+	if opts.maxErrors > 0 || opts.maxConsecutiveErrors > 0 {
+		res.breaker = &circuitBreaker{maxTotal: opts.maxErrors, maxConsecutive: opts.maxConsecutiveErrors}
+	}
+	if opts.requestBudget > 0 {
+		res.budget = newRequestBudget(opts.requestBudget)
+	}
+	if opts.csvStream != "" {
+		stream, err := newCSVStream(opts.csvStream)
+		if err != nil {
+			log.Fatalln("cannot open csv stream:", err)
+		}
+		res.stream = stream
+	}
+	if opts.verboseJSON != "" {
+		events, err := newEventLog(opts.verboseJSON)
+		if err != nil {
+			log.Fatalln("cannot open event log:", err)
+		}
+		res.events = events
+	}
 
 	// Dump a report if we are interrupted before running to completion.
 	channel := make(chan os.Signal, 1)
@@ -45,44 +589,278 @@ func main() {
 	go func() {
 		for sig := range channel {
 			spew.Dump(sig)
-			//printReport(finishReport(pages, heads))
+			if res.stream != nil {
+				_ = res.stream.Close()
+			}
+			//printReport(finishReport(res, &opts))
 			os.Exit(1)
 		}
 	}()
 
-	u, _ := url.Parse(host)
+	stopPprof := func() {}
+	if opts.pprofAddr != "" {
+		stopPprof = startPprof(opts.pprofAddr)
+	}
 
-	c := makeColly(u.Host, heads, pages, &maxVisits, randomDelay, verbose)
+	// closeStreams writes out what's left of -csv-stream and -verbose-json
+	// before we exit.
+	closeStreams := func() {
+		if res.stream != nil {
+			if err := res.stream.Close(); err != nil {
+				log.Fatalln("error writing csv stream:", err)
+			}
+		}
+		if err := res.events.Close(); err != nil {
+			log.Fatalln("error writing event log:", err)
+		}
+	}
 
-	// Visit the first page to kick start the robot
-	_ = c.Visit(u.String())
-	maxVisits--
+	if opts.checkStdin {
+		code, err := checkURLs(os.Stdin, os.Stdout, res, &opts)
+		if err != nil {
+			log.Fatalf("cannot check stdin because %v", err)
+		}
+		stopPprof()
+		closeStreams()
+		os.Exit(code)
+	}
 
-	// Enable if a(sync is true
-	if c.Async {
-		c.Wait()
+	if opts.recheck != "" {
+		code, err := recheck(opts.recheck, os.Stdout, res, &opts)
+		if err != nil {
+			log.Fatalf("cannot recheck %s because %v", opts.recheck, err)
+		}
+		stopPprof()
+		closeStreams()
+		os.Exit(code)
+	}
+
+	if opts.file != "" {
+		checkFile(opts.file, res, &opts)
+	} else if opts.stdin {
+		if err := checkHTML("stdin", os.Stdin, res, &opts); err != nil {
+			log.Fatalf("cannot check stdin because %v", err)
+		}
+	} else if opts.isolateHosts && len(opts.hosts) > 1 {
+		if err := crawlIsolated(res, &opts); err != nil {
+			log.Fatalln(err)
+		}
+	} else if err := crawl(res, &opts); err != nil {
+		log.Fatalln(err)
+	}
+	stopPprof()
+	closeStreams()
+
+	os.Exit(audit(os.Stdout, res, started, color, &opts))
+}
+
+// audit checks what the crawl found, writes the report to w, and any files
+// which were asked for, and returns the exit code.
+func audit(w io.Writer, res *results, started time.Time, color bool, opts *options) int {
+	// The point was only to fill the cache, so a later run can do the
+	// checking without going back to the network for pages.
+	if opts.warmCache {
+		log.Printf("fetched %d pages into .url-cache", len(res.sizes))
+		return 0
+	}
+
+	validateLinks(res, foundLinks(res), opts)
+	if opts.validateSitemap != "" {
+		client, err := sitemapClient(opts.validateSitemap, opts)
+		if err != nil {
+			log.Fatalf("cannot validate sitemap because %v", err)
+		}
+		findings, err := validateSitemap(opts.validateSitemap, time.Now(), client)
+		if err != nil {
+			log.Fatalf("cannot validate sitemap because %v", err)
+		}
+		res.findings = append(res.findings, findings...)
+	}
+	finishChecks(res, opts)
+
+	if res.breaker != nil && res.breaker.tripped() != "" {
+		log.Printf("the crawl stopped early because %s, so this report is incomplete", res.breaker.tripped())
 	}
 
 	log.Println("head report:")
 
-	rows := finishReport(pages, heads, onlyFailures)
-	printReport(rows)
-	if csv {
-		rows2csv(rows)
+	rows := finishReport(res, opts)
+	var meta *runMeta
+	if opts.outputTimestamp {
+		meta = newRunMeta(started, res, opts)
+	}
+
+	printReports(w, res, rows, meta, color, opts)
+	if opts.textfile != "" {
+		if err := writeTextfile(opts.textfile, res, rows, opts); err != nil {
+			log.Fatalln("error writing textfile:", err)
+		}
+	}
+	if opts.emitSitemap != "" {
+		if err := writeSitemap(opts.emitSitemap, res); err != nil {
+			log.Fatalln("error writing sitemap:", err)
+		}
+	}
+	if opts.sqlitePath != "" {
+		if err := rows2sqlite(opts.sqlitePath, res); err != nil {
+			log.Fatalln("error writing sqlite:", err)
+		}
+	}
+	if opts.baselineGenerate != "" {
+		if err := writeBaseline(opts.baselineGenerate, rows, opts.baselineAccept, meta); err != nil {
+			log.Fatalln("error writing baseline:", err)
+		}
+	}
+
+	return exitCode(res, rows, opts)
+}
+
+// printReports writes the report, and whichever of the summaries were asked
+// for, to w.
+func printReports(w io.Writer, res *results, rows linkReport, meta *runMeta, color bool, opts *options) {
+	// With -count-only the count is all that goes to stdout, so that a
+	// script can read it straight into a variable.
+	out := w
+	if opts.countOnly {
+		fmt.Fprintln(w, countFailures(rows, opts))
+		out = io.Discard
+	}
+	if opts.flat {
+		if err := printFlat(out, rows); err != nil {
+			log.Fatalln("error writing report:", err)
+		}
+	} else if opts.groupByStatus {
+		printGroupedReport(out, rows, color, opts.maxURLWidth)
+	} else {
+		printReport(out, rows, color, opts.maxURLWidth)
+	}
+	if opts.githubAnnotations {
+		if err := printAnnotations(out, rows); err != nil {
+			log.Fatalln("error writing annotations:", err)
+		}
+	}
+	if opts.pageSummary {
+		printPageSummary(out, brokenPerPage(res, opts))
+	}
+	if opts.coverage {
+		printCoverage(out, coverage(res, opts))
+	}
+	if opts.detectCycles {
+		printCycles(out, linkCycles(res, opts))
+	}
+	if opts.reportDuplicateLinks {
+		printDuplicateLinks(out, duplicateLinks(res))
+	}
+	if opts.summary {
+		printSummary(out, summarize(res, rows, opts))
+	}
+	if res.budget != nil {
+		printBudget(out, res.budget)
+	}
+	if opts.csv {
+		rows2csv(out, rows, meta)
+	}
+}
+
+func crawl(res *results, opts *options) error {
+	seeds := make([]*url.URL, 0, len(opts.hosts))
+	hosts := make([]string, 0, len(opts.hosts))
+	for _, host := range opts.hosts {
+		u, _ := url.Parse(host)
+		normalizeURL(u, opts)
+		seeds = append(seeds, u)
+		hosts = append(hosts, u.Host)
+	}
+
+	var routes []string
+	if opts.routesFile != "" {
+		var err error
+		if routes, err = readRoutes(opts.routesFile, seeds, opts); err != nil {
+			return fmt.Errorf("cannot read routes because %v", err)
+		}
+	}
+
+	base, err := baseTransport(hosts, opts)
+	if err != nil {
+		return err
 	}
+	return crawlWith(res, opts, seeds, routes, base)
 }
 
-func makeColly(
-	host string,
-	heads headReport,
-	pages pageReport,
-	maxVisits *int,
-	randomDelay int,
-	verbose bool,
-) *colly.Collector {
+// crawlWith crawls from seeds and routes, making its requests with base.
+func crawlWith(res *results, opts *options, seeds []*url.URL, routes []string, base http.RoundTripper) error {
+	hosts := make([]string, 0, len(seeds))
+	for _, u := range seeds {
+		hosts = append(hosts, u.Host)
+	}
+	c, queue, err := makeColly(hosts, res, opts, base)
+	if err != nil {
+		return err
+	}
 
-	// Use this for multiple maps. Not worried about contention.
-	var m = sync.Mutex{}
+	// Visit the first page of each host to kick start the robot
+	// The requests are under way as soon as we visit, and OnRequest looks
+	// at maxVisits, so count them under the lock.
+	for _, u := range seeds {
+		res.Lock()
+		res.depths[u.String()] = 0
+		res.Unlock()
+		queue.visit(c, u.String())
+		res.Lock()
+		opts.maxVisits--
+		res.Unlock()
+	}
+
+	// Routes are seeds too, but we record them as links from the routes
+	// file so that any which don't work show up in the report.
+	for _, route := range routes {
+		res.addLink(opts.routesFile, route, "route")
+		res.Lock()
+		res.foundAt(checkedURL(route, opts), 0)
+		res.Unlock()
+		queue.visit(c, checkedURL(route, opts))
+		res.Lock()
+		opts.maxVisits--
+		res.Unlock()
+	}
+
+	// Enable if a(sync is true
+	if c.Async {
+		queue.wait(c)
+	}
+	return nil
+}
+
+// baseTransport returns the transport which makes the actual requests:
+// the network, a Unix socket or a HAR file.
+func baseTransport(hosts []string, opts *options) (http.RoundTripper, error) {
+	if opts.harFile != "" {
+		replay, err := newHARTransport(opts.harFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load HAR because %v", err)
+		}
+		return replay, nil
+	}
+	if opts.unixSocket != "" {
+		return unixSocketTransport(opts.unixSocket, hosts), nil
+	}
+	return http.DefaultTransport, nil
+}
+
+// makeColly returns a collector which crawls the given hosts and HEAD checks
+// links to anywhere else, along with the queue that requests should go
+// through so that -max-queue is respected. Requests are made by base,
+// usually from baseTransport, underneath our own timeouts, retries and so
+// on, so anything which speaks http.RoundTripper can stand in for the
+// network. Setting up can fail, e.g. if we can't log in, and the error
+// says why.
+func makeColly(hosts []string, res *results, opts *options, base http.RoundTripper) (*colly.Collector, *requestQueue, error) {
+	verbose := opts.verbose
+
+	inScope := map[string]bool{}
+	for _, host := range hosts {
+		inScope[host] = true
+	}
 
 	// maybe create cache directory
 	cacheDir := ".url-cache"
@@ -96,54 +874,347 @@ func makeColly(
 		colly.DisallowedDomains("facebook.com"),
 	)
 
+	// The cache would answer for URLs whether they're in the HAR or not.
+	if opts.harFile != "" {
+		c.CacheDir = ""
+	}
+
 	c.AllowURLRevisit = false
 	c.ParseHTTPErrorResponse = true
+	c.MaxBodySize = opts.maxBodySize
+
+	queue := &requestQueue{max: opts.maxQueue, headed: map[string]bool{}}
+	if opts.dedupeHead {
+		queue.known = func(link string) bool {
+			res.Lock()
+			defer res.Unlock()
+			code := res.heads[link]
+			if code >= 200 && code < 300 && verbose {
+				log.Printf("not checking %v again, it already returned %d", link, code)
+			}
+			return code >= 200 && code < 300
+		}
+	}
+	if opts.externalBatchSize > 0 || opts.externalBatchInterval > 0 {
+		queue.batch = newHeadBatch(opts.externalBatchSize, opts.externalBatchInterval, func(links []string) {
+			if verbose {
+				log.Printf("sending a batch of %d external links", len(links))
+			}
+			for _, link := range links {
+				queue.head(c, link)
+			}
+		})
+	}
+
+	// The TLS settings have to be in place before -allow-insecure-hosts
+	// takes over dialing.
+	if opts.strictTLS {
+		base = strictTLSBase(base)
+	}
+	if len(opts.allowInsecureHosts) > 0 {
+		base = insecureHostsBase(base, opts.allowInsecureHosts)
+	}
+	if opts.strictTLS {
+		base = &tlsTransport{
+			transport:    base,
+			expiryWindow: opts.tlsExpiryWindow,
+			report: func(f Finding) {
+				res.Lock()
+				res.findings = append(res.findings, f)
+				res.Unlock()
+			},
+			seen: map[string]bool{},
+		}
+	}
+	if res.budget != nil {
+		base = &budgetTransport{transport: base, budget: res.budget}
+	}
+
+	// Timeouts are applied per host by the transport. Each retry gets a
+	// timeout of its own.
+	var transport http.RoundTripper = &timeoutTransport{
+		transport: base,
+		timeouts:  opts.hostTimeouts,
+		fallback:  opts.timeout,
+	}
+	if opts.maxResponseTime > 0 {
+		transport = &slowTransport{
+			transport: transport,
+			max:       opts.maxResponseTime,
+			tooSlow: func(link string) {
+				res.Lock()
+				res.tooSlow[link] = true
+				res.Unlock()
+			},
+		}
+	}
+	// Outside of the timeouts, so that waiting to start doesn't count. A
+	// HAR file has no connections to warm up.
+	if opts.connectionWarmup > 0 && opts.harFile == "" {
+		transport = newWarmupTransport(transport, opts.connectionWarmup)
+	}
+	if opts.rampUp > 0 {
+		transport = newRampUpTransport(transport, opts.rampUp)
+	}
+	if opts.crawlWindow.set {
+		transport = &windowTransport{transport: transport, window: &opts.crawlWindow, now: time.Now}
+	}
+	if opts.requestIDHeader != "" {
+		transport = &requestIDTransport{
+			transport: transport,
+			header:    opts.requestIDHeader,
+			record: func(link, id string) {
+				res.Lock()
+				res.requestIDs[link] = id
+				res.Unlock()
+			},
+		}
+	}
+	// Inside the retries, so they get the current token too.
+	if opts.bearerToken != "" || opts.tokenRefreshCmd != "" {
+		transport = &bearerTransport{
+			transport:  transport,
+			token:      opts.bearerToken,
+			refreshCmd: strings.Fields(opts.tokenRefreshCmd),
+			inScope:    inScope,
+		}
+	}
+	if opts.retries > 0 || opts.resetRetries > 0 {
+		transport = &retryTransport{
+			transport: transport,
+			retries:   opts.retries,
+			statuses:  opts.retryStatus,
+			resets:    opts.resetRetries,
+			backoff:   time.Second,
+		}
+	}
+	if res.events != nil {
+		transport = &eventTransport{transport: transport, events: res.events}
+	}
+	c.SetRequestTimeout(0)
+	c.WithTransport(transport)
+
+	// For GETs which have to happen outside of the collector.
+	fallbackClient := &http.Client{Transport: transport}
+
+	if opts.loginURL != "" {
+		jar, err := login(fallbackClient, c.UserAgent, opts.loginURL, opts.loginFields, opts.loginSuccessSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot log in because %v", err)
+		}
+		c.SetCookieJar(jar)
+		fallbackClient.Jar = jar
+	}
+
+	// Hand back the 3xx response itself so that we can report on it.
+	if !opts.followRedirects {
+		c.RedirectHandler = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		fallbackClient.CheckRedirect = c.RedirectHandler
+	}
 
 	c.OnRequest(func(r *colly.Request) {
 		r.Ctx.Put("url", r.URL.String())
-		if r.Method == "GET" && r.URL.Host != "" && r.URL.Host != host {
-			_ = c.Head(r.URL.String())
+		r.ResponseCharacterEncoding = opts.charset
+		if res.budget != nil && res.budget.exhausted() {
+			if verbose {
+				log.Printf("aborting %v, the request budget has run out", r.URL)
+			}
+			r.Abort()
+			queue.release(r.Ctx)
+			return
+		}
+		if res.breaker != nil && res.breaker.tripped() != "" {
+			r.Abort()
+			queue.release(r.Ctx)
+			return
+		}
+		if r.Method == "GET" && r.URL.Host != "" && !inScope[r.URL.Host] && !res.isCanonical(r.URL.Host) {
+			queue.headExternal(c, r.URL.String())
 			if verbose {
 				log.Printf("HEAD %v", r.URL)
 			}
 			r.Abort()
+			queue.release(r.Ctx)
 			return
 		}
-		m.Lock()
-		if *maxVisits > 0 || r.Method == "HEAD" {
-			fmt.Printf("max visits is %v %v %v\n", *maxVisits, r.Method, r.URL.String())
+		res.Lock()
+		overHostLimit := opts.maxPagesPerHost > 0 && res.hostVisits[r.URL.Host] >= opts.maxPagesPerHost
+		if r.Method == "HEAD" || (opts.maxVisits > 0 && !overHostLimit) {
+			if verbose {
+				log.Printf("max visits is %v %v %v", opts.maxVisits, r.Method, r.URL.String())
+			}
 			if r.Method == "GET" {
-				*maxVisits--
+				opts.maxVisits--
+				res.hostVisits[r.URL.Host]++
 			}
 		} else {
 			if verbose {
 				log.Printf("aborting %v over max visits", r.URL)
 			}
 			r.Abort()
+			queue.release(r.Ctx)
 		}
-		m.Unlock()
+		res.Unlock()
 	})
 
+	// learnCanonical notices when one of our hosts redirects to its www or
+	// non-www twin. The twin is the canonical host, so we crawl it too.
+	learnCanonical := func(from, to string) {
+		fromURL, err := url.Parse(from)
+		if err != nil || !inScope[fromURL.Host] {
+			return
+		}
+		toURL, err := url.Parse(to)
+		if err != nil || !wwwVariants(fromURL.Host, toURL.Host) {
+			return
+		}
+
+		res.Lock()
+		_, known := res.canonicalHosts[fromURL.Host]
+		res.canonicalHosts[fromURL.Host] = toURL.Host
+		res.Unlock()
+
+		if !known {
+			if verbose {
+				log.Printf("%v redirects to %v, so crawling that as well", fromURL.Host, toURL.Host)
+			}
+			_ = c.Limit(hostLimit(toURL.Host, opts))
+		}
+	}
+
+	// tripBreaker counts a request towards -max-errors, and says so if
+	// that's the one which stops the crawl.
+	tripBreaker := func(failed bool) {
+		if res.breaker == nil {
+			return
+		}
+		if reason := res.breaker.record(failed); reason != "" {
+			log.Printf("stopping the crawl because %s", reason)
+		}
+	}
+
 	c.OnResponse(func(r *colly.Response) {
-		m.Lock()
-		heads[r.Request.URL.String()] = r.StatusCode
-		m.Unlock()
+		queue.release(r.Ctx)
+		tripBreaker(r.StatusCode >= 500)
+		if r.StatusCode >= 400 {
+			res.events.emit(event{Event: "failure", Method: r.Request.Method, URL: r.Ctx.Get("url"), Status: r.StatusCode})
+		}
+
+		if r.Request.Method == "GET" && r.Request.URL.String() != r.Ctx.Get("url") {
+			learnCanonical(r.Ctx.Get("url"), r.Request.URL.String())
+		}
 
+		// The HTML callbacks run after this and see the decoded body.
+		if opts.charset == "" {
+			decodeBody(r, verbose)
+		}
+		if opts.respectRobots && hasNofollow(strings.Join((*r.Headers)["X-Robots-Tag"], ",")) {
+			r.Ctx.Put("nofollow", "1")
+		}
+
+		res.Lock()
+		res.heads[r.Request.URL.String()] = r.StatusCode
+		res.methods[r.Request.URL.String()] = r.Request.Method
 		if r.Request.URL.String() != r.Ctx.Get("url") {
-			heads[r.Ctx.Get("url")] = r.StatusCode
+			res.heads[r.Ctx.Get("url")] = r.StatusCode
+			res.methods[r.Ctx.Get("url")] = r.Request.Method
+			// colly followed a redirect.
+			res.finalURLs[r.Ctx.Get("url")] = r.Request.URL.String()
+		}
+		if r.Request.Method == "GET" {
+			res.foundAt(r.Request.URL.String(), res.depths[r.Ctx.Get("url")])
+			res.sizes[r.Request.URL.String()] = len(r.Body)
+			if lastModified := r.Headers.Get("Last-Modified"); lastModified != "" {
+				res.lastModified[r.Request.URL.String()] = lastModified
+			}
+		}
+		res.Unlock()
+
+		// A HEAD can work when the download itself doesn't, so for files
+		// we make sure we can have the first byte.
+		if opts.headThenRange && r.Request.Method == "HEAD" && r.StatusCode >= 200 && r.StatusCode < 300 &&
+			!strings.Contains(r.Headers.Get("Content-Type"), "text/html") {
+			link := r.Request.URL.String()
+			status, err := getFirstByte(fallbackClient, c.UserAgent, link)
+			if err == nil && (status == 200 || status == 206) {
+				return
+			}
+			if verbose {
+				log.Printf("range GET of %v failed with %d %v after HEAD returned %d", link, status, err, r.StatusCode)
+			}
+			res.Lock()
+			res.rangeFailed[link] = true
+			res.rangeFailed[r.Ctx.Get("url")] = true
+			res.Unlock()
+			return
 		}
-		// Looks like we never hit this condition :(
-		if r.StatusCode > 299 && r.StatusCode < 399 {
-			fmt.Printf("redirecting  %v to %v\n", r.Ctx.Get("url"), r.Request.URL.String())
-			heads[r.Ctx.Get("url")] = r.StatusCode
-			_ = c.Head(r.Headers.Get("Location"))
+
+		if r.Request.Method == "HEAD" && opts.headFallback[r.StatusCode] {
+			link := r.Request.URL.String()
+			status, err := getInstead(fallbackClient, c.UserAgent, link)
+			if err != nil {
+				if verbose {
+					log.Printf("cannot GET %v after HEAD returned %d because %v", link, r.StatusCode, err)
+				}
+				return
+			}
+			if verbose {
+				log.Printf("GET %v returned %d after HEAD returned %d", link, status, r.StatusCode)
+			}
+
+			res.Lock()
+			for _, u := range []string{link, r.Ctx.Get("url")} {
+				res.heads[u] = status
+				res.methods[u] = "GET"
+			}
+			res.Unlock()
+			return
+		}
+
+		// We only see these when we're not following redirects.
+		if r.StatusCode > 299 && r.StatusCode < 400 {
+			location := r.Request.AbsoluteURL(r.Headers.Get("Location"))
+			if verbose {
+				log.Printf("redirecting %v to %v", r.Ctx.Get("url"), location)
+			}
+
+			res.Lock()
+			res.heads[r.Ctx.Get("url")] = r.StatusCode
+			res.redirects[r.Ctx.Get("url")] = location
+			res.Unlock()
+
+			if location == "" {
+				return
+			}
+			if r.Request.Method == "GET" {
+				learnCanonical(r.Ctx.Get("url"), location)
+			}
+			// Keep crawling through internal redirects, but only check
+			// the targets of HEAD requests.
+			if r.Request.Method == "GET" {
+				queue.visit(c, location)
+			} else {
+				queue.head(c, location)
+			}
 		}
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
-		m.Lock()
-		heads[r.Request.URL.String()] = r.StatusCode
-		m.Unlock()
+		queue.release(r.Ctx)
+		tripBreaker(true)
+		res.events.emit(event{Event: "failure", Method: r.Request.Method, URL: r.Ctx.Get("url"), Error: err.Error()})
+
+		res.Lock()
+		res.heads[r.Request.URL.String()] = r.StatusCode
+		if class := classifyError(err); class != "" {
+			res.errors[r.Request.URL.String()] = class
+			res.errors[r.Ctx.Get("url")] = class
+		}
+		res.Unlock()
+
+		streamChecked(res, opts, r.Request.URL.String(), r.Ctx.Get("url"))
 
 		var link = r.Request.URL
 		if verbose {
@@ -154,35 +1225,102 @@ func makeColly(
 				log.Printf("queuing HEAD request for %v\n", link)
 			}
 
-			_ = c.Head(link.String())
+			queue.head(c, link.String())
 
 			// If the link is http, check if https is available
 			if link.Scheme == "http" && r.Request.Method == "HEAD" {
 				link.Scheme = "https"
-				_ = c.Head(link.String())
+				queue.head(c, link.String())
 			}
 		}
 	})
 
-	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		a := e.Request.AbsoluteURL(e.Attr("href"))
+	// By now the response and any HEAD fallback or redirect have been
+	// recorded.
+	c.OnScraped(func(r *colly.Response) {
+		streamChecked(res, opts, r.Request.URL.String(), r.Ctx.Get("url"))
+	})
+
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		runChecks(e.Response, goquery.NewDocumentFromNode(e.DOM.Nodes[0]), res, opts)
+	})
+
+	// This has to be registered before the link callback, so that we
+	// know about nofollow before we see any links.
+	c.OnHTML("meta[name]", func(e *colly.HTMLElement) {
+		if opts.respectRobots && strings.EqualFold(e.Attr("name"), "robots") && hasNofollow(e.Attr("content")) {
+			e.Request.Ctx.Put("nofollow", "1")
+		}
+	})
+
+	var privateHosts *privateHosts
+	if opts.flagPrivateHosts {
+		privateHosts = newPrivateHosts()
+	}
+
+	// handleLink records a link found on the page req fetched, in the given
+	// element, and queues it up to be checked. Internal links are crawled
+	// too, if crawl is set.
+	handleLink := func(req *colly.Request, href, element string, crawl bool) {
+		if req.Ctx.Get("nofollow") != "" {
+			return
+		}
+
+		a := req.AbsoluteURL(href)
+		if a == "" {
+			return
+		}
 		foundURL, _ := url.Parse(a)
+		// colly drops the fragment, but the link we record keeps it.
+		if ref, err := url.Parse(href); err == nil {
+			foundURL.Fragment = ref.Fragment
+		}
 
-		if foundURL.Scheme == "mailto" {
+		if !opts.schemes[foundURL.Scheme] {
 			if verbose {
 				log.Printf("Skipping %v", foundURL.String())
 			}
+			res.skipLink(req.URL.String(), foundURL.String(), element)
 			return
 		}
 
-		m.Lock()
-		u := e.Request.URL.String()
+		normalizeURL(foundURL, opts)
 
-		if _, ok := pages[u]; !ok {
-			pages[e.Request.URL.String()] = map[string]string{}
+		if !res.screenLink(opts, inScope, privateHosts, req.URL.String(), foundURL, element) {
+			return
 		}
-		pages[e.Request.URL.String()][foundURL.String()] = ""
-		m.Unlock()
+
+		if opts.checkWWW {
+			res.Lock()
+			if _, ok := res.canonicalHosts[foundURL.Host]; ok {
+				res.findings = append(res.findings, Finding{
+					Page:   req.URL.String(),
+					Link:   foundURL.String(),
+					Status: "www-inconsistency",
+				})
+			}
+			res.Unlock()
+		}
+
+		res.addLink(req.URL.String(), foundURL.String(), element)
+		res.events.emit(event{Event: "link", Page: req.URL.String(), URL: foundURL.String(), Element: element})
+		streamLink(res, opts, req.URL.String(), foundURL.String())
+
+		if !crawl {
+			if verbose {
+				log.Printf("adding %v to list of links to HEAD", foundURL.String())
+			}
+			if inScope[foundURL.Host] || res.isCanonical(foundURL.Host) {
+				queue.head(c, checkedURL(foundURL.String(), opts))
+			} else {
+				queue.headExternal(c, checkedURL(foundURL.String(), opts))
+			}
+			return
+		}
+
+		res.Lock()
+		res.foundAt(checkedURL(foundURL.String(), opts), res.depths[req.URL.String()]+1)
+		res.Unlock()
 
 		// Visit any subsequent links we find
 		// Error handling happens in the collector's onError()
@@ -196,73 +1334,573 @@ func makeColly(
 		//foundURL.Fragment = ""
 		//}
 
-		_ = c.Visit(foundURL.String())
+		queue.visit(c, checkedURL(foundURL.String(), opts))
+	}
+
+	c.OnHTML(linkSelector, func(e *colly.HTMLElement) {
+		handleLink(e.Request, e.Attr("href"), e.Name, true)
 	})
 
-	_ = c.Limit(&colly.LimitRule{
-		DomainGlob:  host,
-		Parallelism: 2,
-		RandomDelay: time.Duration(randomDelay) * time.Second,
+	c.OnHTML(embedSelector, func(e *colly.HTMLElement) {
+		handleLink(e.Request, e.Attr(urlAttr(e.Name)), e.Name, false)
 	})
 
-	return c
+	// These are crawled like ordinary links.
+	for _, spec := range opts.extraSelectors {
+		attr := spec.attr
+		c.OnHTML(spec.selector, func(e *colly.HTMLElement) {
+			if href := e.Attr(attr); href != "" {
+				handleLink(e.Request, href, e.Name, true)
+			}
+		})
+	}
+
+	if opts.checks["amp"] {
+		c.OnHTML(ampSelector, func(e *colly.HTMLElement) {
+			handleLink(e.Request, e.Attr("href"), "amphtml", true)
+		})
+	}
+
+	// An action which only takes POSTs may answer our HEAD with 405;
+	// -exclude-status-from-exit=405 keeps that from failing the run.
+	if opts.checks["forms"] {
+		c.OnHTML(formSelector, func(e *colly.HTMLElement) {
+			handleLink(e.Request, e.Attr("action"), "form", false)
+		})
+	}
+
+	// Pages which don't declare an icon get the one at /favicon.ico, so
+	// that's the one we check for them.
+	if opts.checkFavicon {
+		c.OnHTML("html", func(e *colly.HTMLElement) {
+			if e.Response.StatusCode >= 300 {
+				return
+			}
+			declared := false
+			e.DOM.Find(faviconSelector).Each(func(_ int, link *goquery.Selection) {
+				element := iconElement(link)
+				if element == "icon" {
+					declared = true
+				}
+				handleLink(e.Request, link.AttrOr("href", ""), element, false)
+			})
+			if !declared {
+				handleLink(e.Request, defaultFavicon, "favicon", false)
+			}
+		})
+	}
+
+	// Browsers refuse to run a script or apply a stylesheet whose hash
+	// doesn't match its integrity attribute, so the link can be fine and
+	// the page still broken.
+	if opts.checkSRI {
+		resources := &subresources{
+			fetch: func(link string) ([]byte, error) {
+				return fetchBody(fallbackClient, c.UserAgent, link, opts.maxBodySize)
+			},
+			hashes: map[string]map[string]string{},
+		}
+		c.OnHTML(sriSelector, func(e *colly.HTMLElement) {
+			href := e.Attr(urlAttr(e.Name))
+			handleLink(e.Request, href, e.Name, false)
+
+			resourceURL, err := url.Parse(e.Request.AbsoluteURL(href))
+			if err != nil || (resourceURL.Scheme != "http" && resourceURL.Scheme != "https") || isAssumedOK(resourceURL.Host, opts.assumeOKHosts) {
+				return
+			}
+			normalizeURL(resourceURL, opts)
+			link := resourceURL.String()
+			// handleLink didn't check it, so don't download it either.
+			if _, ok := opts.ignore.ignores(link); ok {
+				return
+			}
+			digests, err := resources.digests(link)
+			if err != nil {
+				if verbose {
+					log.Printf("Skipping the integrity check of %v because %v", link, err)
+				}
+				return
+			}
+			if sriMatches(e.Attr("integrity"), digests) {
+				return
+			}
+			res.Lock()
+			res.findings = append(res.findings, Finding{
+				Page:   e.Request.URL.String(),
+				Link:   link,
+				Status: "sri-mismatch",
+			})
+			res.Unlock()
+		})
+	}
+
+	// A meta refresh is a redirect which only browsers follow, so we follow
+	// it by hand. refreshes counts the hops to each page, so that we can
+	// spot chains and give up on long ones.
+	if opts.followMetaRefresh {
+		c.OnHTML(metaRefreshSelector, func(e *colly.HTMLElement) {
+			target, ok := metaRefreshURL(e.Attr("content"))
+			if !ok {
+				return
+			}
+			targetURL, err := url.Parse(e.Request.AbsoluteURL(target))
+			if err != nil || targetURL.Host == "" {
+				return
+			}
+			normalizeURL(targetURL, opts)
+
+			page := e.Request.URL.String()
+			res.Lock()
+			hops := res.refreshes[page] + 1
+			if _, ok := res.refreshes[checkedURL(targetURL.String(), opts)]; !ok {
+				res.refreshes[checkedURL(targetURL.String(), opts)] = hops
+			}
+			status := ""
+			switch {
+			case hops > opts.maxMetaRefresh:
+				status = "too-many-meta-refreshes"
+			case hops > 1:
+				status = "meta-refresh-chain"
+			}
+			if status != "" {
+				res.findings = append(res.findings, Finding{Page: page, Link: targetURL.String(), Status: status})
+			}
+			res.Unlock()
+
+			if hops > opts.maxMetaRefresh {
+				if verbose {
+					log.Printf("not following the meta refresh on %v after %d hops", page, hops-1)
+				}
+				return
+			}
+			handleLink(e.Request, target, "meta-refresh", true)
+		})
+	}
+
+	if opts.checkJSONLD {
+		c.OnHTML(jsonldSelector, func(e *colly.HTMLElement) {
+			links, err := jsonldLinks(e.Text)
+			if err != nil {
+				if verbose {
+					log.Printf("Skipping JSON-LD on %v because %v", e.Request.URL, err)
+				}
+				return
+			}
+			for _, link := range links {
+				handleLink(e.Request, link, "jsonld", false)
+			}
+		})
+	}
+
+	// Resource hints in the Link header are checked like embedded content,
+	// under the rel they were given.
+	if opts.checkResourceHints {
+		c.OnResponse(func(r *colly.Response) {
+			if r.Request.Method != "GET" {
+				return
+			}
+			for target, rel := range resourceHints((*r.Headers)["Link"]) {
+				handleLink(r.Request, target, rel, false)
+			}
+		})
+	}
+
+	// Feeds are crawled like links, and then we check the link of every
+	// item in them. External feeds are only HEADed, so we GET those here,
+	// as long as they say they're a feed; we're not going to download
+	// every external XML file to find out.
+	if opts.checkFeeds {
+		c.OnHTML(feedSelector, func(e *colly.HTMLElement) {
+			handleLink(e.Request, e.Attr("href"), "feed", true)
+		})
+
+		c.OnResponse(func(r *colly.Response) {
+			link := r.Request.URL.String()
+			contentType := r.Headers.Get("Content-Type")
+			if r.StatusCode < 200 || r.StatusCode >= 300 || !isFeed(contentType, r.Request.Method == "GET") {
+				return
+			}
+
+			data := r.Body
+			if r.Request.Method == "HEAD" {
+				var err error
+				data, err = fetchBody(fallbackClient, c.UserAgent, link, opts.maxBodySize)
+				if err != nil {
+					if verbose {
+						log.Printf("Skipping feed %v because %v", link, err)
+					}
+					return
+				}
+			}
+			links, err := feedLinks(data)
+			if err != nil {
+				if verbose {
+					log.Printf("Skipping feed %v because %v", link, err)
+				}
+				return
+			}
+			for _, item := range links {
+				handleLink(r.Request, item, "feed-item", false)
+			}
+		})
+	}
+
+	// PDFs don't go through the HTML callbacks, so we dig the links out of
+	// them here. A HEAD only tells us that it's a PDF, so for those we have
+	// to GET it as well.
+	if opts.checkPDF {
+		c.OnResponse(func(r *colly.Response) {
+			link := r.Request.URL.String()
+			if r.StatusCode < 200 || r.StatusCode >= 300 || !isPDF(r.Headers.Get("Content-Type"), link) {
+				return
+			}
+
+			data := r.Body
+			if r.Request.Method == "HEAD" {
+				var err error
+				data, err = fetchBody(fallbackClient, c.UserAgent, link, opts.maxBodySize)
+				if err != nil {
+					if verbose {
+						log.Printf("Skipping PDF %v because %v", link, err)
+					}
+					return
+				}
+			}
+			for _, uri := range pdfLinks(data) {
+				handleLink(r.Request, uri, "pdf-link", false)
+			}
+		})
+	}
+
+	// Markdown and text files don't go through the HTML callbacks either,
+	// and need the same GET after a HEAD.
+	if opts.checkTextLinks {
+		c.OnResponse(func(r *colly.Response) {
+			link := r.Request.URL.String()
+			if r.StatusCode < 200 || r.StatusCode >= 300 || !isTextResource(r.Headers.Get("Content-Type"), link) {
+				return
+			}
+
+			data := r.Body
+			if r.Request.Method == "HEAD" {
+				var err error
+				data, err = fetchBody(fallbackClient, c.UserAgent, link, opts.maxBodySize)
+				if err != nil {
+					if verbose {
+						log.Printf("Skipping text file %v because %v", link, err)
+					}
+					return
+				}
+			}
+			for _, uri := range textLinks(data) {
+				handleLink(r.Request, uri, "text-link", false)
+			}
+		})
+	}
+
+	// Listing archives may only paginate with JavaScript, but still tell
+	// crawlers about the next and previous pages.
+	if opts.followPagination {
+		c.OnHTML(`link[rel~="next"][href], link[rel~="prev"][href]`, func(e *colly.HTMLElement) {
+			handleLink(e.Request, e.Attr("href"), e.Name, true)
+		})
+	}
+
+	if err := c.Limits(collyLimits(hosts, opts)); err != nil {
+		return nil, nil, fmt.Errorf("cannot set limit rules because %v", err)
+	}
+
+	return c, queue, nil
+}
+
+// checkDelays is an error if -min-delay and -max-delay make no sense
+// together. -max-delay on its own is a range starting at zero.
+func checkDelays(opts *options) error {
+	if opts.maxDelay != 0 && opts.maxDelay < opts.minDelay {
+		return errors.New("-max-delay cannot be less than -min-delay")
+	}
+	return nil
+}
+
+// crawlDelay returns the fixed and random parts of the delay between
+// requests. colly waits Delay plus up to RandomDelay, so -min-delay and
+// -max-delay map straight on to those. Without them we wait up to
+// -random-delay seconds, as we always have.
+func crawlDelay(opts *options) (time.Duration, time.Duration) {
+	if opts.minDelay == 0 && opts.maxDelay == 0 {
+		return 0, time.Duration(opts.randomDelay) * time.Second
+	}
+	if opts.maxDelay < opts.minDelay {
+		return opts.minDelay, 0
+	}
+	return opts.minDelay, opts.maxDelay - opts.minDelay
+}
+
+// classifyError describes a request which failed without a response, or
+// returns "" if it's nothing we know how to describe.
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns-error"
+	}
+	if errors.Is(err, errRequestBudget) {
+		return "over-budget"
+	}
+	if errors.Is(err, errNotInHAR) {
+		return "not-in-har"
+	}
+	if isConnectionReset(err) {
+		return "connection-reset"
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+	// net/http only speaks http and https, so this is what we get when
+	// someone adds another scheme to -schemes.
+	if strings.Contains(err.Error(), "unsupported protocol scheme") {
+		return "unsupported-scheme"
+	}
+	return ""
 }
 
 /*
 Report format:
-source page | link found on page | link status code | HTTPS link (if previous link HTTP) | HTTPS link status code
+source page | link found on page | link status code | HTTPS link (if previous link HTTP) | HTTPS link status code | redirect target (if not following redirects) | where the link ends up after any redirects | element the link was found in | crawl depth of the source page | severity | URL to use instead, where we know it | the -request-id-header we last sent for the link
 */
 
-func finishReport(pages pageReport, heads headReport, onlyFailures bool) linkReport {
+const (
+	sourceColumn = iota
+	linkColumn
+	statusColumn
+	httpsLinkColumn
+	httpsStatusColumn
+	locationColumn
+	finalURLColumn
+	elementColumn
+	depthColumn
+	severityColumn
+	suggestedFixColumn
+	requestIDColumn
+	reportColumns
+)
+
+var reportHeader = []string{
+	"Source Page",
+	"Link",
+	"Status",
+	"HTTPS Link",
+	"HTTPS Status",
+	"Location",
+	"Final URL",
+	"Element",
+	"Depth",
+	"Severity",
+	"Suggested Fix",
+	"Request ID",
+}
+
+func finishReport(res *results, opts *options) linkReport {
 	rows := make([][]string, 0)
 
-	// Weed out success URLs for now
-	for sourcePage := range pages {
+	// Rather than a row for every link which bounced us to the login page,
+	// there's one row saying so. Without -login-url there was no session to
+	// lose, and a site which sends most links to one page is worth seeing.
+	var loginPage string
+	var bounced int
+	if opts.loginURL != "" {
+		loginPage, bounced = sessionLost(res)
+	}
+	if loginPage != "" {
+		log.Printf("%d links redirect to %v, which looks like we lost the session; try logging in again with -login-url", bounced, loginPage)
+		row := make([]string, reportColumns)
+		row[sourceColumn] = fmt.Sprintf("%d links", bounced)
+		row[linkColumn] = loginPage
+		row[statusColumn] = "session-lost"
+		rows = append(rows, row)
+	}
 
-		for link := range pages[sourcePage] {
-			row := make([]string, 5)
+	if opts.checks[brokenCheck] {
+		// Weed out success URLs for now
+		for sourcePage := range res.pages {
 
-			linkStatusCode := heads[link]
+			for link := range res.pages[sourcePage] {
+				row := make([]string, reportColumns)
 
-			// XXX find out why some HEAD requests aren't happening
-			if linkStatusCode == 200 || linkStatusCode == 0 {
-				continue
-			}
+				checked := checkedURL(link, opts)
+				_, status := res.linkStatus(link, checked)
+				insecure := opts.treatHTTPAsFailure && isHTTP(link)
 
-			row[0] = sourcePage
-			row[1] = link
-			row[2] = strconv.Itoa(linkStatusCode)
+				// XXX find out why some HEAD requests aren't happening
+				if status == "" || ((acceptable(status, opts) || notChecked(status)) && !opts.all && !insecure) {
+					continue
+				}
 
-			linkURL, _ := url.Parse(link)
-			if linkURL.Scheme == "http" {
-				linkURL.Scheme = "https"
-				httpsLinkStatusCode := heads[row[3]]
-				if onlyFailures && httpsLinkStatusCode == 200 {
+				if loginPage != "" && (res.redirects[checked] == loginPage || res.finalURLs[checked] == loginPage) {
 					continue
 				}
 
-				if httpsLinkStatusCode != 0 {
-					row[4] = strconv.Itoa(httpsLinkStatusCode)
+				row[sourceColumn] = sourcePage
+				row[linkColumn] = link
+				row[statusColumn] = status
+
+				linkURL, _ := url.Parse(link)
+				if linkURL.Scheme == "http" {
+					linkURL.Scheme = "https"
+					row[httpsLinkColumn] = linkURL.String()
+					httpsLinkStatusCode := res.heads[checkedURL(row[httpsLinkColumn], opts)]
+					if opts.onlyFailures && acceptable(strconv.Itoa(httpsLinkStatusCode), opts) && !insecure {
+						continue
+					}
+
+					if httpsLinkStatusCode != 0 {
+						row[httpsStatusColumn] = strconv.Itoa(httpsLinkStatusCode)
+					}
 				}
+				row[locationColumn] = res.redirects[checked]
+				row[finalURLColumn] = res.finalURL(link, checked)
+				row[elementColumn] = res.pages[sourcePage][link]
+				rows = append(rows, row)
 			}
-			rows = append(rows, row)
 		}
 	}
+
+	for _, f := range res.findings {
+		row := make([]string, reportColumns)
+		row[sourceColumn] = f.Page
+		row[linkColumn] = f.Link
+		row[statusColumn] = f.Status
+		rows = append(rows, row)
+	}
+
+	// Findings aren't about an element, so they go as well.
+	if len(opts.reportTypes) > 0 {
+		kept := rows[:0]
+		for _, row := range rows {
+			if opts.reportTypes[row[elementColumn]] {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+
+	// Links are ignored before we check them, but a check can still find
+	// something wrong with one.
+	if len(opts.ignore) > 0 {
+		kept := rows[:0]
+		for _, row := range rows {
+			if _, ok := opts.ignore.ignores(row[linkColumn]); !ok {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+
+	if len(opts.baseline) > 0 {
+		kept := rows[:0]
+		for _, row := range rows {
+			if !opts.baseline.accepts(row) {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+
+	for _, row := range rows {
+		if depth, ok := res.depths[row[sourceColumn]]; ok {
+			row[depthColumn] = strconv.Itoa(depth)
+		}
+		row[severityColumn] = statusSeverity(row[statusColumn], opts)
+		if opts.treatHTTPAsFailure && isHTTP(row[linkColumn]) {
+			row[severityColumn] = severityError
+		}
+		row[suggestedFixColumn] = suggestFix(row, res)
+		row[requestIDColumn] = res.requestIDs[checkedURL(row[linkColumn], opts)]
+	}
+
+	if len(opts.statusPriority) > 0 {
+		sortByPriority(rows, opts.statusPriority)
+	}
+
+	if opts.listReferrers {
+		rows = groupReferrers(rows)
+	}
 	return rows
 }
 
-func printReport(rows linkReport) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Source Page", "Link", "Status", "SSL Status"})
-	table.AppendBulk(rows)
+// groupReferrers collapses the rows for each link into a single row which
+// lists every page the link was found on. Everything other than the source
+// page only depends on the link, so the rest of the row is shared.
+func groupReferrers(rows linkReport) linkReport {
+	grouped := make([][]string, 0)
+	referrers := map[string][]string{}
+	index := map[string]int{}
+
+	for _, row := range rows {
+		link := row[linkColumn]
+		if _, ok := index[link]; !ok {
+			index[link] = len(grouped)
+			grouped = append(grouped, row)
+		}
+		referrers[link] = append(referrers[link], row[sourceColumn])
+	}
+
+	for link, i := range index {
+		sort.Strings(referrers[link])
+		grouped[i][sourceColumn] = strings.Join(referrers[link], "\n")
+	}
+	return grouped
+}
+
+// printReport prints the report as a table. URLs longer than maxURLWidth
+// are cut short, if it's set.
+func printReport(w io.Writer, rows linkReport, color bool, maxURLWidth int) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(reportHeader)
+
+	for _, row := range rows {
+		if color || maxURLWidth > 0 {
+			// Don't touch the row itself, the CSV gets the same rows.
+			row = append([]string(nil), row...)
+		}
+		if color {
+			row[statusColumn] = colorStatus(row[statusColumn])
+			row[httpsStatusColumn] = colorStatus(row[httpsStatusColumn])
+		}
+		if maxURLWidth > 0 {
+			for _, column := range []int{sourceColumn, linkColumn, httpsLinkColumn, locationColumn, finalURLColumn, suggestedFixColumn} {
+				row[column] = truncateURLs(row[column], maxURLWidth)
+			}
+		}
+		table.Append(row)
+	}
 
 	table.Render() // Send output
 }
 
-func rows2csv(rows linkReport) {
+// truncateURLs cuts each line of urls, since -list-referrers puts one per
+// line, down to width characters, ending with an ellipsis.
+func truncateURLs(urls string, width int) string {
+	lines := strings.Split(urls, "\n")
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > width {
+			lines[i] = string(runes[:width-1]) + "…"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rows2csv writes the report as CSV to out, and to report.csv, after the
+// comment lines for meta if there is any.
+func rows2csv(out io.Writer, rows linkReport, meta *runMeta) {
 
 	{
-		w := csv.NewWriter(os.Stdout)
+		if meta != nil {
+			if err := meta.writeComments(out); err != nil {
+				log.Fatalln("error writing csv:", err)
+			}
+		}
+		w := csv.NewWriter(out)
 		_ = w.WriteAll(rows) // calls Flush internally
 
 		if err := w.Error(); err != nil {
@@ -283,6 +1921,11 @@ func rows2csv(rows linkReport) {
 		}
 		defer file.Close()
 
+		if meta != nil {
+			if err := meta.writeComments(file); err != nil {
+				log.Fatalln("error writing csv:", err)
+			}
+		}
 		w := csv.NewWriter(file)
 		_ = w.WriteAll(rows) // calls Flush internally
 
@@ -0,0 +1,771 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubPage is what stubSite answers with for a URL. headStatus, if it's
+// set, is the status for HEAD requests, for servers which answer those
+// differently.
+type stubPage struct {
+	status      int
+	headStatus  int
+	contentType string
+	body        string
+	location    string
+	header      map[string]string
+}
+
+// stubSite is a RoundTripper which answers from pages rather than the
+// network, and counts the requests it gets, by method and URL, e.g.
+// "HEAD http://example.com/". URLs it doesn't know are 404s.
+type stubSite struct {
+	sync.Mutex
+	pages    map[string]stubPage
+	requests map[string]int
+}
+
+func newStubSite(pages map[string]stubPage) *stubSite {
+	return &stubSite{pages: pages, requests: map[string]int{}}
+}
+
+func (s *stubSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	link := req.URL.String()
+	s.Lock()
+	s.requests[req.Method+" "+link]++
+	page, ok := s.pages[link]
+	s.Unlock()
+
+	if !ok {
+		page = stubPage{status: 404}
+	}
+	header := http.Header{}
+	if page.contentType != "" {
+		header.Set("Content-Type", page.contentType)
+	}
+	if page.location != "" {
+		header.Set("Location", page.location)
+	}
+	for name, value := range page.header {
+		header.Set(name, value)
+	}
+	body := page.body
+	status := page.status
+	if req.Method == "HEAD" {
+		body = ""
+		if page.headStatus != 0 {
+			status = page.headStatus
+		}
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (s *stubSite) count(method, link string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests[method+" "+link]
+}
+
+// html is a 200 text/html page.
+func html(body string) stubPage {
+	return stubPage{status: 200, contentType: "text/html; charset=utf-8", body: "<html><body>" + body + "</body></html>"}
+}
+
+// testOptions parses args as the command line would be, without the
+// random delay between requests.
+func testOptions(t *testing.T, args ...string) *options {
+	t.Helper()
+	var opts options
+	fs := flag.NewFlagSet("robocop", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &opts)
+	if err := fs.Parse(append([]string{"-random-delay=0"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	enableChecks(&opts)
+	return &opts
+}
+
+// testCrawl crawls site from seed and returns what we found, along with
+// the report.
+func testCrawl(t *testing.T, site http.RoundTripper, seed string, opts *options) (*results, linkReport) {
+	t.Helper()
+	res := newResults()
+	return res, testCrawlInto(t, res, site, seed, opts)
+}
+
+// testCrawlInto is testCrawl for results which main would have set up
+// some more, e.g. with a request budget. It runs in a temporary
+// directory, so that .url-cache doesn't answer for the site's pages next
+// time.
+func testCrawlInto(t *testing.T, res *results, site http.RoundTripper, seed string, opts *options) linkReport {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	u, err := url.Parse(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.hosts = stringList{seed}
+	if err := crawlWith(res, opts, []*url.URL{u}, nil, site); err != nil {
+		t.Fatal(err)
+	}
+	finishChecks(res, opts)
+	return finishReport(res, opts)
+}
+
+// reportRow is a row of the report with only the source page, link and
+// status filled in.
+func reportRow(source, link, status string) []string {
+	row := make([]string, len(reportHeader))
+	row[sourceColumn] = source
+	row[linkColumn] = link
+	row[statusColumn] = status
+	return row
+}
+
+// reported returns link<space>status for each row of the report, sorted.
+func reported(rows linkReport) []string {
+	got := []string{}
+	for _, row := range rows {
+		got = append(got, row[linkColumn]+" "+row[statusColumn])
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestCrawlReport(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="/about">About</a>
+			<a href="/missing">Missing</a>
+			<a href="/old">Old</a>
+			<a href="http://other.test/fine">Fine</a>
+			<a href="http://other.test/gone">Gone</a>
+			<a href="mailto:me@example.com">Mail</a>`),
+		"http://example.com/about": html(`<a href="/">Home</a><iframe src="/broken.html"></iframe>`),
+		"http://example.com/old":   {status: 301, location: "http://example.com/about"},
+		"http://other.test/fine":   {status: 200},
+		"http://other.test/gone":   {status: 410},
+		"http://other.test/":       {status: 500},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		// wantLocations are the redirect targets reported for links.
+		wantLocations map[string]string
+	}{
+		{
+			name: "failures",
+			want: []string{
+				"http://example.com/broken.html 404",
+				"http://example.com/missing 404",
+				"http://other.test/gone 410",
+			},
+		},
+		{
+			name: "without following redirects",
+			args: []string{"-follow-redirects=false"},
+			want: []string{
+				"http://example.com/broken.html 404",
+				"http://example.com/missing 404",
+				"http://example.com/old 301",
+				"http://other.test/gone 410",
+			},
+			wantLocations: map[string]string{"http://example.com/old": "http://example.com/about"},
+		},
+		{
+			name: "everything",
+			args: []string{"-all"},
+			want: []string{
+				"http://example.com/ 200",
+				"http://example.com/about 200",
+				"http://example.com/broken.html 404",
+				"http://example.com/missing 404",
+				"http://example.com/old 200",
+				"http://other.test/fine 200",
+				"http://other.test/gone 410",
+				"mailto:me@example.com skipped",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			got := reported(rows)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, row := range rows {
+				if want := tt.wantLocations[row[linkColumn]]; row[locationColumn] != want {
+					t.Errorf("got %v redirecting to %q, want %q", row[linkColumn], row[locationColumn], want)
+				}
+			}
+		})
+	}
+}
+
+func TestImageMap(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<img src="/plan.png" usemap="#plan">
+			<map name="plan">
+				<area shape="rect" coords="0,0,10,10" href="/kitchen" alt="Kitchen">
+				<area shape="rect" coords="10,0,20,10" href="/missing" alt="Missing">
+				<area shape="default" nohref alt="Nowhere">
+			</map>`),
+		"http://example.com/plan.png": {status: 200, contentType: "image/png"},
+		"http://example.com/kitchen":  html(`<a href="/">Home</a>`),
+	})
+	_, rows := testCrawl(t, site, "http://example.com/", testOptions(t))
+
+	want := []string{"http://example.com/missing 404"}
+	if got := reported(rows); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got report %v, want %v", got, want)
+	}
+	// Areas are links like any other, so we crawl the pages they go to.
+	if site.count("GET", "http://example.com/kitchen") != 1 {
+		t.Errorf("got requests %v, want the page the area links to crawled", site.requests)
+	}
+}
+
+func TestFollowPagination(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<link rel="next" href="/page/2"><a href="/">Home</a>`),
+		"http://example.com/page/2": html(`<link rel="prev" href="/"><link rel="next prefetch" href="/page/3">
+			<a href="/gone">Gone</a>`),
+		"http://example.com/page/3": html(`<link rel="prev" href="/page/2">`),
+	})
+
+	tests := []struct {
+		name  string
+		args  []string
+		want  []string
+		pages []string
+	}{
+		{name: "not followed", want: []string{}},
+		{
+			name:  "followed",
+			args:  []string{"-follow-pagination"},
+			want:  []string{"http://example.com/gone 404"},
+			pages: []string{"http://example.com/", "http://example.com/page/2", "http://example.com/page/3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.requests = map[string]int{}
+			res, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			if got := reported(rows); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got report %v, want %v", got, tt.want)
+			}
+			for _, page := range tt.pages {
+				if _, ok := res.pages[page]; !ok || site.count("GET", page) != 1 {
+					t.Errorf("got requests %v, want %v crawled once", site.requests, page)
+				}
+			}
+			if len(tt.pages) == 0 && site.count("GET", "http://example.com/page/2") != 0 {
+				t.Errorf("got requests %v, want the next page left alone", site.requests)
+			}
+		})
+	}
+}
+
+func TestSchemes(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":     html(`<a href="ftp://files.example.com/gone.zip">Download</a><a href="/fine">Fine</a>`),
+		"http://example.com/fine": html(""),
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     []string
+		requests int
+	}{
+		{name: "skipped by default", want: []string{"ftp://files.example.com/gone.zip skipped"}},
+		{
+			name:     "checked when listed",
+			args:     []string{"-schemes=http,https,ftp"},
+			want:     []string{"ftp://files.example.com/gone.zip 404"},
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site.requests = map[string]int{}
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, append([]string{"-all"}, tt.args...)...))
+			got := []string{}
+			for _, row := range reported(rows) {
+				if strings.HasPrefix(row, "ftp:") {
+					got = append(got, row)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got report %v, want %v", got, tt.want)
+			}
+			if n := site.count("HEAD", "ftp://files.example.com/gone.zip") + site.count("GET", "ftp://files.example.com/gone.zip"); n != tt.requests {
+				t.Errorf("got %d requests for the ftp link, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestDepths(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/section">Section</a><a href="/missing-0">Missing</a>`),
+		// A link back up doesn't make the home page any deeper.
+		"http://example.com/section":      html(`<a href="/section/page">Page</a><a href="/">Home</a><a href="/missing-1">Missing</a>`),
+		"http://example.com/section/page": html(`<a href="/missing-2">Missing</a><a href="/section">Section</a>`),
+	})
+	res, rows := testCrawl(t, site, "http://example.com/", testOptions(t))
+
+	pages := map[string]int{
+		"http://example.com/":             0,
+		"http://example.com/section":      1,
+		"http://example.com/section/page": 2,
+	}
+	for page, want := range pages {
+		if got, ok := res.depths[page]; !ok || got != want {
+			t.Errorf("got %v at depth %d, want %d", page, got, want)
+		}
+	}
+
+	got := []string{}
+	for _, row := range rows {
+		got = append(got, row[sourceColumn]+" "+row[depthColumn])
+	}
+	sort.Strings(got)
+	want := []string{
+		"http://example.com/ 0",
+		"http://example.com/section 1",
+		"http://example.com/section/page 2",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got depths %q, want %q", got, want)
+	}
+}
+
+func TestCheckWWW(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":        {status: 301, location: "http://www.example.com/"},
+		"http://example.com/contact": {status: 301, location: "http://www.example.com/contact"},
+		"http://www.example.com/": html(`<a href="/about">About</a>
+			<a href="http://example.com/contact">Contact</a>`),
+		"http://www.example.com/about":   html(""),
+		"http://www.example.com/contact": html(""),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "not checked", want: []string{}},
+		{
+			name: "checked",
+			args: []string{"-check-www"},
+			want: []string{"http://www.example.com/ http://example.com/contact www-inconsistency"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[sourceColumn]+" "+row[linkColumn]+" "+row[statusColumn])
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got report %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportTypes(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/missing">Missing</a>
+			<img src="/missing.png">
+			<script src="/missing.js"></script>
+			<iframe src="/missing.html"></iframe>`),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "everything",
+			want: []string{
+				"http://example.com/missing 404",
+				"http://example.com/missing.html 404",
+				"http://example.com/missing.js 404",
+				"http://example.com/missing.png 404",
+			},
+		},
+		{name: "only images", args: []string{"-report-types=img"}, want: []string{"http://example.com/missing.png 404"}},
+		{
+			name: "images and scripts",
+			args: []string{"-report-types=img,script"},
+			want: []string{"http://example.com/missing.js 404", "http://example.com/missing.png 404"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// We only check images and scripts when asked to.
+			args := append([]string{"-extra-selectors=img[src]@src", "-extra-selectors=script[src]@src"}, tt.args...)
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, args...))
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	pages := map[string]stubPage{
+		"http://example.com/":      html(`<a href="/about">About</a><a href="/missing">Missing</a>`),
+		"http://example.com/about": html(`<a href="/">Home</a>`),
+	}
+	t.Chdir(t.TempDir())
+	u, _ := url.Parse("http://example.com/")
+	crawlOnce := func(site *stubSite, args ...string) (*results, string, int) {
+		t.Helper()
+		opts := testOptions(t, args...)
+		opts.hosts = stringList{u.String()}
+		res := newResults()
+		if err := crawlWith(res, opts, []*url.URL{u}, nil, site); err != nil {
+			t.Fatal(err)
+		}
+		var stdout bytes.Buffer
+		code := audit(&stdout, res, time.Now(), false, opts)
+		return res, stdout.String(), code
+	}
+
+	_, stdout, code := crawlOnce(newStubSite(pages), "-warm-cache")
+	if stdout != "" || code != 0 {
+		t.Errorf("got exit code %d and stdout %q, want 0 and no report", code, stdout)
+	}
+	entries, err := os.ReadDir(".url-cache")
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("got %d entries in .url-cache (%v), want the pages", len(entries), err)
+	}
+
+	// The pages come from the cache now, so a site which has lost them
+	// doesn't matter.
+	offline := newStubSite(map[string]stubPage{})
+	res, stdout, code := crawlOnce(offline)
+	if !strings.Contains(stdout, "http://example.com/missing") || code != 1 {
+		t.Errorf("got exit code %d and stdout %q, want the report", code, stdout)
+	}
+	for page := range pages {
+		if n := offline.count("GET", page); n != 0 {
+			t.Errorf("got %d GETs of %v, want it from the cache", n, page)
+		}
+	}
+	if _, ok := res.pages["http://example.com/about"]; !ok {
+		t.Errorf("got pages %v, want the about page crawled from the cache", res.pages)
+	}
+}
+
+func TestListReferrers(t *testing.T) {
+	gone := `<a href="/gone">Gone</a><a href="http://other.test/">Elsewhere</a>`
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":  html(`<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`),
+		"http://example.com/a": html(gone),
+		"http://example.com/b": html(gone),
+		"http://example.com/c": html(gone),
+		"http://other.test/":   {status: 500},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "a row per page",
+			want: []string{
+				"http://example.com/a http://example.com/gone",
+				"http://example.com/a http://other.test/",
+				"http://example.com/b http://example.com/gone",
+				"http://example.com/b http://other.test/",
+				"http://example.com/c http://example.com/gone",
+				"http://example.com/c http://other.test/",
+			},
+		},
+		{
+			name: "every referrer in one row",
+			args: []string{"-list-referrers"},
+			want: []string{
+				"http://example.com/a\nhttp://example.com/b\nhttp://example.com/c http://example.com/gone",
+				"http://example.com/a\nhttp://example.com/b\nhttp://example.com/c http://other.test/",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[sourceColumn]+" "+row[linkColumn])
+			}
+			sort.Strings(got)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	urlError := func(err error) error {
+		return &url.Error{Op: "Head", URL: "http://example.com/", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "no such host", err: urlError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nowhere.invalid", IsNotFound: true}}), want: "dns-error"},
+		{name: "DNS timeout", err: urlError(&net.DNSError{Err: "i/o timeout", Name: "slow.test", IsTimeout: true}), want: "dns-error"},
+		{name: "anything else", err: urlError(errors.New("connection refused")), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// dnsSite is site, except that .invalid hosts don't resolve.
+type dnsSite struct{ site *stubSite }
+
+func (s dnsSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Hostname(), ".invalid") {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: req.URL.Hostname(), IsNotFound: true}}
+	}
+	return s.site.RoundTrip(req)
+}
+
+func TestDNSError(t *testing.T) {
+	site := dnsSite{newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="http://nowhere.invalid/">Gone</a><a href="http://other.test/">Fine</a>`),
+		"http://other.test/":  {status: 200},
+	})}
+	_, rows := testCrawl(t, site, "http://example.com/", testOptions(t))
+	want := []string{"http://nowhere.invalid/ dns-error"}
+	if got := reported(rows); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got report %v, want %v", got, want)
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantDelay  time.Duration
+		wantRandom time.Duration
+		wantErr    bool
+	}{
+		{name: "-random-delay", args: []string{"-random-delay=3"}, wantRandom: 3 * time.Second},
+		{name: "a range", args: []string{"-min-delay=1s", "-max-delay=3s"}, wantDelay: time.Second, wantRandom: 2 * time.Second},
+		{name: "the range overrides -random-delay", args: []string{"-random-delay=10", "-min-delay=1s", "-max-delay=3s"}, wantDelay: time.Second, wantRandom: 2 * time.Second},
+		{name: "the same at both ends", args: []string{"-min-delay=2s", "-max-delay=2s"}, wantDelay: 2 * time.Second},
+		{name: "only a minimum", args: []string{"-min-delay=2s"}, wantDelay: 2 * time.Second},
+		{name: "only a maximum", args: []string{"-max-delay=500ms"}, wantRandom: 500 * time.Millisecond},
+		{name: "minimum over the maximum", args: []string{"-min-delay=3s", "-max-delay=1s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts options
+			fs := flag.NewFlagSet("robocop", flag.ContinueOnError)
+			defineFlags(fs, &opts)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkDelays(&opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			delay, random := crawlDelay(&opts)
+			if delay != tt.wantDelay || random != tt.wantRandom {
+				t.Errorf("got %v plus up to %v, want %v plus up to %v", delay, random, tt.wantDelay, tt.wantRandom)
+			}
+		})
+	}
+}
+
+func TestMakeCollyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		args []string
+		want string
+	}{
+		{
+			// The stub site 404s the login page.
+			name: "login fails",
+			host: "example.com",
+			args: []string{"-login-url=http://example.com/login", "-login-fields=user=me"},
+			want: "cannot log in",
+		},
+		{
+			// -limit-rule checks its globs, but the hosts we crawl go
+			// straight in.
+			name: "bad host glob",
+			host: "exa[mple.com",
+			want: "cannot set limit rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			site := newStubSite(map[string]stubPage{})
+			_, _, err := makeColly([]string{tt.host}, newResults(), testOptions(t, tt.args...), site)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one about %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseTransportMissingHAR(t *testing.T) {
+	_, err := baseTransport(nil, testOptions(t, "-har=does-not-exist.har"))
+	if err == nil || !strings.Contains(err.Error(), "cannot load HAR") {
+		t.Errorf("got error %v, want one about loading the HAR", err)
+	}
+}
+
+func TestTruncateURLs(t *testing.T) {
+	tests := []struct {
+		urls  string
+		width int
+		want  string
+	}{
+		{urls: "http://example.com/", width: 30, want: "http://example.com/"},
+		{urls: "http://example.com/", width: 19, want: "http://example.com/"},
+		{urls: "http://example.com/", width: 18, want: "http://example.co…"},
+		{urls: "http://example.com/ünïcödé", width: 22, want: "http://example.com/ün…"},
+		{
+			urls:  "http://example.com/a-long-page\nhttp://example.com/",
+			width: 20,
+			want:  "http://example.com/…\nhttp://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urls, func(t *testing.T) {
+			if got := truncateURLs(tt.urls, tt.width); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxURLWidth(t *testing.T) {
+	long := "http://example.com/" + strings.Repeat("a", 100) + "?q=" + strings.Repeat("b", 100)
+	rows := linkReport{reportRow("http://example.com/", long, "404")}
+
+	tests := []struct {
+		name     string
+		width    int
+		color    bool
+		wantFull bool
+	}{
+		{name: "no limit", wantFull: true},
+		{name: "cut short", width: 40},
+		{name: "cut short with color", width: 40, color: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+
+			var table bytes.Buffer
+			printReport(&table, rows, tt.color, tt.width)
+			if got := strings.Contains(table.String(), long); got != tt.wantFull {
+				t.Errorf("got the whole URL in the table %v, want %v:\n%s", got, tt.wantFull, table.String())
+			}
+			if !tt.wantFull && !strings.Contains(table.String(), long[:39]+"…") {
+				t.Errorf("the table doesn't have the URL cut to %d characters:\n%s", tt.width, table.String())
+			}
+
+			var csv bytes.Buffer
+			rows2csv(&csv, rows, nil)
+			if !strings.Contains(csv.String(), long) {
+				t.Errorf("the CSV doesn't have the whole URL:\n%s", csv.String())
+			}
+			if rows[0][linkColumn] != long {
+				t.Errorf("printReport changed the row to %q", rows[0][linkColumn])
+			}
+		})
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":      html(`<a href="/missing">Missing</a><a href="/gone">Gone</a><a href="/moved">Moved</a>`),
+		"http://example.com/moved": {status: 301, location: "/"},
+		"http://example.com/gone":  {status: 410},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "failures", want: "2\n"},
+		{name: "with warnings as errors", args: []string{"-warnings-as-errors"}, want: "3\n"},
+		{name: "with everything else on stdout", args: []string{"-csv", "-summary", "-page-summary"}, want: "2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, append([]string{"-count-only", "-follow-redirects=false"}, tt.args...)...)
+			res, rows := testCrawl(t, site, "http://example.com/", opts)
+
+			var stdout bytes.Buffer
+			printReports(&stdout, res, rows, nil, false, opts)
+			if stdout.String() != tt.want {
+				t.Errorf("got stdout %q, want %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+)
+
+// hasNofollow reports whether a robots directive list, from either a
+// <meta name="robots"> tag or an X-Robots-Tag header, tells us not to
+// follow links. Header values may be scoped to a user agent, as in
+// "googlebot: nofollow", which we treat as applying to us too.
+func hasNofollow(directives string) bool {
+	for _, directive := range strings.Split(strings.ToLower(directives), ",") {
+		if i := strings.LastIndex(directive, ":"); i != -1 {
+			directive = directive[i+1:]
+		}
+		switch strings.TrimSpace(directive) {
+		case "nofollow", "none":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasNofollow(t *testing.T) {
+	tests := []struct {
+		directives string
+		want       bool
+	}{
+		{directives: "nofollow", want: true},
+		{directives: "noindex, nofollow", want: true},
+		{directives: "NOFOLLOW", want: true},
+		{directives: "none", want: true},
+		{directives: "googlebot: nofollow", want: true},
+		{directives: "noindex"},
+		{directives: "index, follow"},
+		{directives: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.directives, func(t *testing.T) {
+			if got := hasNofollow(tt.directives); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespectMetaRobots(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/meta">Meta</a><a href="/header">Header</a>`),
+		"http://example.com/meta": {
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><head><meta name="robots" content="noindex, nofollow"></head><body><a href="/from-meta">x</a></body></html>`,
+		},
+		"http://example.com/header": {
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><body><a href="/from-header">x</a></body></html>`,
+			header:      map[string]string{"X-Robots-Tag": "nofollow"},
+		},
+	})
+
+	tests := []struct {
+		args     []string
+		followed bool
+	}{
+		{followed: true},
+		{args: []string{"-respect-meta-robots"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			site.requests = map[string]int{}
+			res, _ := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			for _, page := range []string{"http://example.com/meta", "http://example.com/header"} {
+				if _, got := res.pages[page]; got != tt.followed {
+					t.Errorf("got links recorded for %v %v, want %v", page, got, tt.followed)
+				}
+			}
+			for _, link := range []string{"http://example.com/from-meta", "http://example.com/from-header"} {
+				requested := site.count("GET", link)+site.count("HEAD", link) > 0
+				if requested != tt.followed {
+					t.Errorf("got %v requested %v, want %v", link, requested, tt.followed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// readRoutes returns the URLs of the routes listed in path, one per line,
+// for -routes. Single page apps build their links with JavaScript, which
+// we can't see, so this is how we find out about them. A route like
+// /app/dashboard is resolved against each of the seeds; absolute URLs are
+// left as they are.
+func readRoutes(path string, seeds []*url.URL, opts *options) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	routes := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ref, err := url.Parse(line)
+		if err != nil {
+			return nil, err
+		}
+		if ref.IsAbs() {
+			normalizeURL(ref, opts)
+			routes = append(routes, ref.String())
+			continue
+		}
+		for _, seed := range seeds {
+			u := seed.ResolveReference(ref)
+			normalizeURL(u, opts)
+			routes = append(routes, u.String())
+		}
+	}
+	return routes, scanner.Err()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  string
+		seeds   []string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "resolved against the seed",
+			routes: "/app/dashboard\n\n  # the settings pages\n/app/settings?tab=1 \nhelp",
+			seeds:  []string{"https://example.com/docs/"},
+			want: []string{
+				"https://example.com/app/dashboard",
+				"https://example.com/app/settings?tab=1",
+				"https://example.com/docs/help",
+			},
+		},
+		{
+			name:   "each seed",
+			routes: "/app",
+			seeds:  []string{"https://example.com/", "https://other.test/"},
+			want:   []string{"https://example.com/app", "https://other.test/app"},
+		},
+		{
+			name:   "absolute URLs are normalized",
+			routes: "HTTPS://Example.COM:443/app?b=2&a=1",
+			seeds:  []string{"https://example.com/", "https://other.test/"},
+			args:   []string{"-sort-query"},
+			want:   []string{"https://example.com/app?a=1&b=2"},
+		},
+		{
+			name:    "not a URL",
+			routes:  "/app\n%zz",
+			seeds:   []string{"https://example.com/"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "routes.txt")
+			if err := os.WriteFile(path, []byte(tt.routes), 0644); err != nil {
+				t.Fatal(err)
+			}
+			seeds := []*url.URL{}
+			for _, seed := range tt.seeds {
+				u, err := url.Parse(seed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				seeds = append(seeds, u)
+			}
+
+			got, err := readRoutes(path, seeds, testOptions(t, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got routes\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// extraSelector is a custom link given as selector@attr, e.g.
+// [data-href]@data-href for frameworks which don't use a[href].
+type extraSelector struct {
+	selector string
+	attr     string
+}
+
+// extraSelectors implements flag.Value for -extra-selectors. Selectors can
+// have commas in them, so each one needs its own flag rather than a comma
+// separated list.
+type extraSelectors []extraSelector
+
+func (l *extraSelectors) String() string {
+	specs := make([]string, 0, len(*l))
+	for _, spec := range *l {
+		specs = append(specs, spec.selector+"@"+spec.attr)
+	}
+	return strings.Join(specs, " ")
+}
+
+func (l *extraSelectors) Set(value string) error {
+	i := strings.LastIndex(value, "@")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("%q is not in selector@attr format", value)
+	}
+	spec := extraSelector{selector: value[:i], attr: value[i+1:]}
+	if _, err := cascadia.Compile(spec.selector); err != nil {
+		return fmt.Errorf("bad selector %q: %v", spec.selector, err)
+	}
+	*l = append(*l, spec)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtraSelectorsSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "[data-href]@data-href", want: "[data-href]@data-href"},
+		{value: "div.card, li.item@data-url", want: "div.card, li.item@data-url"},
+		{value: `a[title="me@example.com"]@data-href`, want: `a[title="me@example.com"]@data-href`},
+		{value: "[data-href]", wantErr: true},
+		{value: "@data-href", wantErr: true},
+		{value: "[data-href]@", wantErr: true},
+		{value: "[data-href@data-href", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var selectors extraSelectors
+			err := selectors.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if got := selectors.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtraSelectorsCrawl(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<div data-href="/card">Card</div>
+			<button data-url="/gone">Gone</button>
+			<div data-href="">Empty</div>`),
+		"http://example.com/card": html(`<div data-href="/deeper">Deeper</div>`),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "without", want: []string{}},
+		{
+			// Their pages are crawled too.
+			name: "with",
+			args: []string{"-extra-selectors=[data-href]@data-href", "-extra-selectors=button@data-url"},
+			want: []string{"http://example.com/deeper 404", "http://example.com/gone 404"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
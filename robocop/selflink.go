@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// selfLinkCheck flags links back to the page they're on, which are usually
+// a mistake. In-page anchors like #top or /page#top are fine, since the
+// fragment makes them a different URL. colly drops the fragment when it
+// makes the link absolute, so we put it back before comparing, as
+// handleLink does.
+type selfLinkCheck struct{}
+
+func (selfLinkCheck) Name() string {
+	return "self"
+}
+
+func (selfLinkCheck) Run(page *colly.Response, doc *goquery.Document) []Finding {
+	source := page.Request.URL.String()
+
+	findings := []Finding{}
+	doc.Find(linkSelector).Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		link := page.Request.AbsoluteURL(href)
+		if link == "" {
+			return
+		}
+		if ref, err := url.Parse(href); err == nil && ref.Fragment != "" {
+			if u, err := url.Parse(link); err == nil {
+				u.Fragment = ref.Fragment
+				link = u.String()
+			}
+		}
+		if strings.TrimSuffix(link, "/") == strings.TrimSuffix(source, "/") {
+			findings = append(findings, Finding{
+				Page:   source,
+				Link:   link,
+				Status: "self-link",
+			})
+		}
+	})
+	return findings
+}
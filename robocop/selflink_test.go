@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// testPage makes the response and document a Check is given, for the
+// HTML body of pageURL.
+func testPage(t *testing.T, pageURL, body string) (*colly.Response, *goquery.Document) {
+	t.Helper()
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &colly.Response{StatusCode: 200, Request: &colly.Request{URL: u}, Body: []byte(body)}, doc
+}
+
+func TestSelfLinkCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		href string
+		want bool
+	}{
+		{name: "same page", href: "/docs/page", want: true},
+		{name: "trailing slash", href: "/docs/page/", want: true},
+		{name: "absolute", href: "https://example.com/docs/page", want: true},
+		{name: "in-page anchor", href: "#top"},
+		{name: "anchor with the path", href: "/docs/page#top"},
+		{name: "relative anchor", href: "page#section"},
+		{name: "another page", href: "/docs/other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, doc := testPage(t, "https://example.com/docs/page", `<a href="`+tt.href+`">x</a>`)
+			findings := selfLinkCheck{}.Run(page, doc)
+			if got := len(findings) > 0; got != tt.want {
+				t.Errorf("%q: got self-link %v, want %v (%v)", tt.href, got, tt.want, findings)
+			}
+		})
+	}
+}
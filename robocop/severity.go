@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	severityOK      = "ok"
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+// warningStatuses are the statuses other than 3xx which are worth looking
+// at but aren't broken.
+var warningStatuses = map[string]bool{
+	"amp-canonical-mismatch": true,
+	"duplicate-description":  true,
+	"future-lastmod":         true,
+	"invalid-lastmod":        true,
+	"meta-refresh-chain":     true,
+	"mismatched-anchor":      true,
+	"missing-amp-canonical":  true,
+	"missing-amphtml":        true,
+	"missing-description":    true,
+	"mixed-content":          true,
+	"over-budget":            true,
+	"self-link":              true,
+	"session-lost":           true,
+	"tls-expiring":           true,
+	"www-inconsistency":      true,
+}
+
+// severity sorts a report row's status into ok, warning or error. 2xx is
+// ok and 3xx is a warning. Anything else, including the statuses we use
+// for requests which never got a response, like dns-error, is an error.
+func severity(status string) string {
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		switch {
+		case notChecked(status):
+			return severityOK
+		case warningStatuses[status]:
+			return severityWarning
+		}
+		return severityError
+	}
+
+	switch {
+	case code < 300:
+		return severityOK
+	case code < 400:
+		return severityWarning
+	}
+	return severityError
+}
+
+// statusSeverity is a status's severity once -warn-status and -fail-on
+// have had their say, e.g. so that a 204 for a page which should have
+// content can be a warning or an error.
+func statusSeverity(status string, opts *options) string {
+	if code, err := strconv.Atoi(status); err == nil {
+		switch {
+		case opts.failOn[code]:
+			return severityError
+		case opts.warnStatus[code]:
+			return severityWarning
+		}
+	}
+	return severity(status)
+}
+
+// acceptable is true for the 2xx statuses we leave out of the report,
+// which is all of them other than the ones -warn-status or -fail-on pick
+// out.
+func acceptable(status string, opts *options) bool {
+	code, err := strconv.Atoi(status)
+	return err == nil && code >= 200 && code < 300 && statusSeverity(status, opts) == severityOK
+}
+
+// health is the percentage of the links on pages we checked which came
+// back 2xx. Links which never got a response count against it.
+func health(res *results, opts *options) float64 {
+	checked := checkedLinks(res, opts)
+	if len(checked) == 0 {
+		return 100
+	}
+	healthy := 0
+	for _, status := range checked {
+		if status >= 200 && status < 300 {
+			healthy++
+		}
+	}
+	return float64(healthy) * 100 / float64(len(checked))
+}
+
+// isHTTP is true for plain http links, which -treat-http-as-failure
+// reports as errors whatever their status.
+func isHTTP(link string) bool {
+	return strings.HasPrefix(strings.ToLower(link), "http:")
+}
+
+// exitCode is non-zero if the report has any errors in it, or any warnings
+// with -warnings-as-errors. With -min-health we go by the health score
+// instead, so that a handful of broken links on a big site doesn't fail
+// the build. A crawl which -max-errors cut short always fails.
+func exitCode(res *results, rows linkReport, opts *options) int {
+	if res.breaker != nil && res.breaker.tripped() != "" {
+		return 1
+	}
+
+	if opts.minHealth > 0 {
+		if health(res, opts) < opts.minHealth {
+			return 1
+		}
+		return 0
+	}
+
+	if countFailures(rows, opts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// countFailures is the number of rows in the report which fail the run,
+// for -count-only.
+func countFailures(rows linkReport, opts *options) int {
+	failures := 0
+	for _, row := range rows {
+		if failsRun(row[statusColumn], row[severityColumn], opts) {
+			failures++
+		}
+	}
+	return failures
+}
+
+// failsRun is true if a status with the given severity should make us exit
+// non-zero. Statuses in -exclude-status-from-exit are still reported, but
+// never fail the run.
+func failsRun(status, sev string, opts *options) bool {
+	if opts.excludeFromExit[status] {
+		return false
+	}
+	return sev == severityError || (sev == severityWarning && opts.warningsAsErrors)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name  string
+		links map[string]int
+		want  float64
+	}{
+		{name: "nothing checked", want: 100},
+		{
+			name:  "all good",
+			links: map[string]int{"http://example.com/a": 200, "http://example.com/b": 204},
+			want:  100,
+		},
+		{
+			name: "half broken",
+			links: map[string]int{
+				"http://example.com/a": 200,
+				"http://example.com/b": 404,
+			},
+			want: 50,
+		},
+		{
+			name: "no response counts against it",
+			links: map[string]int{
+				"http://example.com/a":    200,
+				"http://example.com/b":    200,
+				"http://example.com/c":    301,
+				"http://nowhere.invalid/": 0,
+			},
+			want: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			// Pages and https probes are in res.heads, but aren't links
+			// on the page, so they don't count either way.
+			res.heads["http://example.com/"] = 200
+			res.heads["https://example.com/b"] = 200
+			res.heads["http://example.com/gone"] = 404
+			for link, status := range tt.links {
+				res.addLink("http://example.com/", link, "a")
+				if status == 0 {
+					res.errors[link] = "dns-error"
+					continue
+				}
+				res.heads[link] = status
+			}
+
+			if got := health(res, &options{}); got != tt.want {
+				t.Errorf("got health %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusSeverity(t *testing.T) {
+	tests := []struct {
+		status string
+		args   []string
+		want   string
+	}{
+		{status: "200", want: severityOK},
+		{status: "204", want: severityOK},
+		{status: "204", args: []string{"-warn-status=204"}, want: severityWarning},
+		{status: "204", args: []string{"-fail-on=204"}, want: severityError},
+		{status: "204", args: []string{"-warn-status=204", "-fail-on=204"}, want: severityError},
+		{status: "206", args: []string{"-warn-status=204,206"}, want: severityWarning},
+		{status: "200", args: []string{"-warn-status=204", "-fail-on=206"}, want: severityOK},
+		{status: "301", want: severityWarning},
+		{status: "404", args: []string{"-warn-status=404"}, want: severityWarning},
+		{status: "dns-error", args: []string{"-warn-status=204"}, want: severityError},
+		{status: "missing-amphtml", want: severityWarning},
+		{status: "missing-amp-canonical", want: severityWarning},
+		{status: "amp-canonical-mismatch", want: severityWarning},
+	}
+
+	for _, tt := range tests {
+		name := tt.status + " " + strings.Join(tt.args, " ")
+		t.Run(name, func(t *testing.T) {
+			if got := statusSeverity(tt.status, testOptions(t, tt.args...)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarningsAsErrors(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":    html(`<a href="/old">Old</a><a href="/new">New</a>`),
+		"http://example.com/old": {status: 301, location: "/new"},
+		"http://example.com/new": html(""),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		exit int
+	}{
+		{name: "warnings", exit: 0},
+		{name: "warnings as errors", args: []string{"-warnings-as-errors"}, exit: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, append([]string{"-follow-redirects=false"}, tt.args...)...)
+			res, rows := testCrawl(t, site, "http://example.com/", opts)
+
+			want := []string{"http://example.com/old 301 warning"}
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[linkColumn]+" "+row[statusColumn]+" "+row[severityColumn])
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+			if code := exitCode(res, rows, opts); code != tt.exit {
+				t.Errorf("got exit code %d, want %d", code, tt.exit)
+			}
+		})
+	}
+}
+
+func TestTreatHTTPAsFailure(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"https://example.com/": html(`<a href="http://other.test/">Insecure</a><a href="https://other.test/">Secure</a>`),
+		"http://other.test/":   html(""),
+		"https://other.test/":  html(""),
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		exit int
+	}{
+		{name: "working http links are fine", want: []string{}},
+		{
+			name: "strict",
+			args: []string{"-treat-http-as-failure"},
+			want: []string{"http://other.test/ 200 error"},
+			exit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, tt.args...)
+			res, rows := testCrawl(t, site, "https://example.com/", opts)
+
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[linkColumn]+" "+row[statusColumn]+" "+row[severityColumn])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if code := exitCode(res, rows, opts); code != tt.exit {
+				t.Errorf("got exit code %d, want %d", code, tt.exit)
+			}
+		})
+	}
+}
+
+func TestExcludeStatusFromExit(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/missing">Missing</a><a href="http://other.test/">Down</a>`),
+		"http://other.test/":  {status: 503},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		exit int
+	}{
+		{name: "nothing excluded", exit: 1},
+		{name: "404 excluded", args: []string{"-exclude-status-from-exit=404"}, exit: 1},
+		{name: "both excluded", args: []string{"-exclude-status-from-exit=404,503"}, exit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, tt.args...)
+			res, rows := testCrawl(t, site, "http://example.com/", opts)
+
+			// Excluded statuses are still reported.
+			want := []string{"http://example.com/missing 404", "http://other.test/ 503"}
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+			if code := exitCode(res, rows, opts); code != tt.exit {
+				t.Errorf("got exit code %d, want %d", code, tt.exit)
+			}
+		})
+	}
+}
+
+func TestWarnStatusAndFailOn(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<a href="/fine">Fine</a>
+			<a href="/created">Created</a>
+			<a href="/empty">Empty</a>
+			<a href="/partial">Partial</a>`),
+		"http://example.com/fine":    html(""),
+		"http://example.com/created": {status: 201},
+		"http://example.com/empty":   {status: 204},
+		"http://example.com/partial": {status: 206},
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		exit int
+	}{
+		{name: "2xx is fine", want: []string{}},
+		{
+			name: "-warn-status",
+			args: []string{"-warn-status=204"},
+			want: []string{"http://example.com/empty 204 warning"},
+		},
+		{
+			name: "-warn-status with -warnings-as-errors",
+			args: []string{"-warn-status=204", "-warnings-as-errors"},
+			want: []string{"http://example.com/empty 204 warning"},
+			exit: 1,
+		},
+		{
+			name: "-fail-on",
+			args: []string{"-fail-on=204"},
+			want: []string{"http://example.com/empty 204 error"},
+			exit: 1,
+		},
+		{
+			name: "both",
+			args: []string{"-warn-status=206", "-fail-on=204"},
+			want: []string{
+				"http://example.com/empty 204 error",
+				"http://example.com/partial 206 warning",
+			},
+			exit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, tt.args...)
+			res, rows := testCrawl(t, site, "http://example.com/", opts)
+
+			got := []string{}
+			for _, row := range rows {
+				got = append(got, row[linkColumn]+" "+row[statusColumn]+" "+row[severityColumn])
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if code := exitCode(res, rows, opts); code != tt.exit {
+				t.Errorf("got exit code %d, want %d", code, tt.exit)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// writeSitemap writes a sitemap.xml listing every page we crawled which
+// came back 2xx. lastmod comes from the page's Last-Modified header, if it
+// sent a valid one.
+func writeSitemap(path string, res *results) error {
+	pages := make([]string, 0, len(res.sizes))
+	for page := range res.sizes {
+		if status := res.heads[page]; status >= 200 && status < 300 {
+			pages = append(pages, page)
+		}
+	}
+	sort.Strings(pages)
+
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, page := range pages {
+		entry := sitemapURL{Loc: page}
+		if t, err := http.ParseTime(res.lastModified[page]); err == nil {
+			entry.LastMod = t.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		file.Close()
+		return err
+	}
+	enc := xml.NewEncoder(file)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.WriteString("\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// sitemapEntries is a sitemap as we read it for -validate-sitemap. A
+// sitemap index lists sitemaps rather than pages, but their entries look
+// the same, so we take either.
+type sitemapEntries struct {
+	URLs     []sitemapURL `xml:"url"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// lastModLayouts are the W3C datetime formats sitemaps allow. RFC 3339
+// takes care of fractions of a second too.
+var lastModLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	time.RFC3339,
+}
+
+// validateSitemap reads the sitemap at path, a file or an http(s) URL, and
+// returns a finding for every entry whose lastmod isn't a date we can read,
+// invalid-lastmod, or is in the future, future-lastmod. Dates are allowed
+// a day's grace, since a date without a time could be today somewhere
+// ahead of us. A sitemap URL is fetched with client.
+func validateSitemap(path string, now time.Time, client *http.Client) ([]Finding, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		resp, err := client.Get(path)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned %d", path, resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r = file
+	}
+	defer r.Close()
+
+	var sitemap sitemapEntries
+	if err := xml.NewDecoder(r).Decode(&sitemap); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+
+	findings := []Finding{}
+	for _, entry := range append(sitemap.URLs, sitemap.Sitemaps...) {
+		lastMod := strings.TrimSpace(entry.LastMod)
+		if lastMod == "" {
+			continue
+		}
+		t, ok := parseLastMod(lastMod)
+		switch {
+		case !ok:
+			findings = append(findings, Finding{Page: path, Link: entry.Loc, Status: "invalid-lastmod"})
+		case t.After(now.Add(24 * time.Hour)):
+			findings = append(findings, Finding{Page: path, Link: entry.Loc, Status: "future-lastmod"})
+		}
+	}
+	return findings, nil
+}
+
+// sitemapClient fetches a -validate-sitemap URL the way we make our other
+// requests: over -unix-socket or from -har if we're using them, and with
+// -timeout (or the sitemap host's -host-timeout).
+func sitemapClient(path string, opts *options) (*http.Client, error) {
+	hosts := []string{}
+	if u, err := url.Parse(path); err == nil {
+		hosts = append(hosts, u.Host)
+	}
+	base, err := baseTransport(hosts, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: &timeoutTransport{
+		transport: base,
+		timeouts:  opts.hostTimeouts,
+		fallback:  opts.timeout,
+	}}, nil
+}
+
+func parseLastMod(lastMod string) (time.Time, bool) {
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
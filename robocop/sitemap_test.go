@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateSitemap(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		lastMod string
+		want    string
+	}{
+		{name: "none"},
+		{name: "a date", lastMod: "2024-05-31"},
+		{name: "a year", lastMod: "2024"},
+		{name: "with the time", lastMod: "2024-05-31T10:00:00+01:00"},
+		{name: "later today somewhere", lastMod: "2024-06-02"},
+		{name: "next week", lastMod: "2024-06-08", want: "future-lastmod"},
+		{name: "not a date", lastMod: "yesterday", want: "invalid-lastmod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sitemap.xml")
+			sitemap := `<urlset><url><loc>http://example.com/</loc><lastmod>` + tt.lastMod + `</lastmod></url></urlset>`
+			if err := os.WriteFile(path, []byte(sitemap), 0666); err != nil {
+				t.Fatal(err)
+			}
+
+			findings, err := validateSitemap(path, now, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if len(findings) > 0 {
+				got = findings[0].Status
+			}
+			if got != tt.want || len(findings) > 1 {
+				t.Errorf("got findings %v, want %q", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSitemapTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.xml" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`<urlset><url><loc>http://example.com/</loc><lastmod>nope</lastmod></url></urlset>`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/sitemap.xml"},
+		{path: "/slow.xml", want: "deadline exceeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			sitemap := server.URL + tt.path
+			client, err := sitemapClient(sitemap, testOptions(t, "-timeout=100ms"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings, err := validateSitemap(sitemap, time.Now(), client)
+			if tt.want == "" {
+				if err != nil || len(findings) != 1 {
+					t.Errorf("got findings %v and error %v, want one invalid-lastmod", findings, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one about the -timeout", err)
+			}
+		})
+	}
+}
+
+func TestWriteSitemap(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`<a href="/about">About</a><a href="/missing">Missing</a><a href="/broken">Broken</a>
+			<a href="http://other.test/">Elsewhere</a>`),
+		"http://example.com/about": {
+			status:      200,
+			contentType: "text/html",
+			body:        `<a href="/">Home</a>`,
+			header:      map[string]string{"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT"},
+		},
+		"http://example.com/broken": {status: 500, contentType: "text/html", body: `<a href="/">Home</a>`},
+		"http://other.test/":        html(""),
+	})
+	res, _ := testCrawl(t, site, "http://example.com/", testOptions(t))
+
+	path := filepath.Join(t.TempDir(), "sitemap.xml")
+	if err := writeSitemap(path, res); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set sitemapURLSet
+	if err := xml.Unmarshal(data, &set); err != nil {
+		t.Fatalf("got invalid XML %v:\n%s", err, data)
+	}
+
+	want := []sitemapURL{
+		{Loc: "http://example.com/"},
+		{Loc: "http://example.com/about", LastMod: "2015-10-21T07:28:00Z"},
+	}
+	if fmt.Sprint(set.URLs) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", set.URLs, want)
+	}
+	if set.Xmlns != "http://www.sitemaps.org/schemas/sitemap/0.9" {
+		t.Errorf("got namespace %q", set.Xmlns)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// slowTransport calls tooSlow with the URL of any request which took more
+// than max to get a response, for -max-response-time.
+type slowTransport struct {
+	transport http.RoundTripper
+	max       time.Duration
+	tooSlow   func(link string)
+}
+
+func (t *slowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil && time.Since(start) > t.max {
+		t.tooSlow(req.URL.String())
+	}
+	return resp, err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// delayedTransport answers from transport, but only after the delay for
+// the URL.
+type delayedTransport struct {
+	transport http.RoundTripper
+	delays    map[string]time.Duration
+}
+
+func (t delayedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	time.Sleep(t.delays[req.URL.String()])
+	return t.transport.RoundTrip(req)
+}
+
+func TestSlowTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		want      bool
+	}{
+		{name: "fast", transport: newStubSite(map[string]stubPage{})},
+		{
+			name: "slow",
+			transport: delayedTransport{
+				transport: newStubSite(map[string]stubPage{}),
+				delays:    map[string]time.Duration{"http://example.com/": 50 * time.Millisecond},
+			},
+			want: true,
+		},
+		// A timeout is reported as one, not as too slow.
+		{name: "no response", transport: &timeoutTransport{transport: hangingTransport{}, fallback: 50 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := false
+			transport := &slowTransport{
+				transport: tt.transport,
+				max:       20 * time.Millisecond,
+				tooSlow:   func(string) { got = true },
+			}
+			req, _ := http.NewRequest("HEAD", "http://example.com/", nil)
+			if resp, err := transport.RoundTrip(req); err == nil {
+				resp.Body.Close()
+			}
+			if got != tt.want {
+				t.Errorf("got too slow %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxResponseTime(t *testing.T) {
+	site := delayedTransport{
+		transport: newStubSite(map[string]stubPage{
+			"http://example.com/":     html(`<a href="/fast">Fast</a><a href="/slow">Slow</a><a href="/gone">Gone</a>`),
+			"http://example.com/fast": html(""),
+			"http://example.com/slow": html(""),
+		}),
+		delays: map[string]time.Duration{
+			"http://example.com/slow": 100 * time.Millisecond,
+			"http://example.com/gone": 100 * time.Millisecond,
+		},
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "without a limit", want: []string{"http://example.com/gone 404"}},
+		{
+			// A broken link is reported as broken, however long it took.
+			name: "with a limit",
+			args: []string{"-max-response-time=50ms"},
+			want: []string{"http://example.com/gone 404", "http://example.com/slow too-slow"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := testCrawl(t, site, "http://example.com/", testOptions(t, tt.args...))
+			if got := reported(rows); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got report\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+
+	// Pure Go driver, so we don't need cgo just to write a report.
+	_ "modernc.org/sqlite"
+)
+
+// How many rows to insert per transaction.
+const sqliteBatchSize = 500
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS pages (
+	url TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS links (
+	source TEXT NOT NULL REFERENCES pages(url),
+	link   TEXT NOT NULL,
+	PRIMARY KEY (source, link)
+);
+CREATE TABLE IF NOT EXISTS statuses (
+	url    TEXT PRIMARY KEY,
+	status INTEGER NOT NULL,
+	method TEXT
+);
+`
+
+// rows2sqlite writes everything we found during the crawl to a SQLite
+// database, so that large audits can be queried with plain SQL. e.g.
+//
+//	SELECT l.source, l.link, s.status FROM links l
+//	JOIN statuses s ON s.url = l.link WHERE s.status >= 400;
+func rows2sqlite(path string, res *results) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+
+	b := &sqliteBatch{db: db}
+
+	for page := range res.pages {
+		if err := b.exec("INSERT OR IGNORE INTO pages (url) VALUES (?)", page); err != nil {
+			return err
+		}
+	}
+
+	for page := range res.pages {
+		for link := range res.pages[page] {
+			if err := b.exec(
+				"INSERT OR IGNORE INTO links (source, link) VALUES (?, ?)",
+				page, link,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	for link, status := range res.heads {
+		if err := b.exec(
+			"INSERT OR REPLACE INTO statuses (url, status, method) VALUES (?, ?, ?)",
+			link, status, res.methods[link],
+		); err != nil {
+			return err
+		}
+	}
+
+	return b.commit()
+}
+
+// sqliteBatch groups inserts into transactions of sqliteBatchSize rows.
+// Inserting row by row outside of a transaction is painfully slow once a
+// crawl gets into the tens of thousands of links.
+type sqliteBatch struct {
+	db    *sql.DB
+	tx    *sql.Tx
+	count int
+}
+
+func (b *sqliteBatch) exec(query string, args ...interface{}) error {
+	if b.tx == nil {
+		tx, err := b.db.Begin()
+		if err != nil {
+			return err
+		}
+		b.tx = tx
+	}
+
+	if _, err := b.tx.Exec(query, args...); err != nil {
+		_ = b.tx.Rollback()
+		b.tx = nil
+		return err
+	}
+
+	b.count++
+	if b.count%sqliteBatchSize == 0 {
+		return b.commit()
+	}
+	return nil
+}
+
+func (b *sqliteBatch) commit() error {
+	if b.tx == nil {
+		return nil
+	}
+	err := b.tx.Commit()
+	b.tx = nil
+	return err
+}
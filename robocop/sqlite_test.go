@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestRows2sqliteFailureCounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		heads    map[string]int
+		links    int
+		failures int
+	}{
+		{
+			name:  "nothing broken",
+			heads: map[string]int{"https://example.com/a": 200, "https://example.com/b": 301},
+			links: 2,
+		},
+		{
+			name: "some broken",
+			heads: map[string]int{
+				"https://example.com/a": 200,
+				"https://example.com/b": 404,
+				"https://example.com/c": 500,
+				"https://example.com/d": 410,
+			},
+			links:    4,
+			failures: 3,
+		},
+		{
+			// More than one batch, so that we know the later
+			// transactions are committed too.
+			name:     "several batches",
+			heads:    manyHeads(sqliteBatchSize*2+7, 404),
+			links:    sqliteBatchSize*2 + 7,
+			failures: sqliteBatchSize*2 + 7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newResults()
+			for link, status := range tt.heads {
+				res.addLink("https://example.com/", link, "a")
+				res.heads[link] = status
+				res.methods[link] = "HEAD"
+			}
+
+			path := filepath.Join(t.TempDir(), "audit.db")
+			if err := rows2sqlite(path, res); err != nil {
+				t.Fatal(err)
+			}
+
+			db, err := sql.Open("sqlite", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if got := count(t, db, "SELECT COUNT(*) FROM links"); got != tt.links {
+				t.Errorf("got %d links, want %d", got, tt.links)
+			}
+			failures := count(t, db, `SELECT COUNT(*) FROM links l
+				JOIN statuses s ON s.url = l.link WHERE s.status >= 400`)
+			if failures != tt.failures {
+				t.Errorf("got %d failures, want %d", failures, tt.failures)
+			}
+		})
+	}
+}
+
+func manyHeads(n, status int) map[string]int {
+	heads := map[string]int{}
+	for i := 0; i < n; i++ {
+		heads[fmt.Sprintf("https://example.com/%d", i)] = status
+	}
+	return heads
+}
+
+func count(t *testing.T, db *sql.DB, query string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"sync"
+)
+
+// sriSelector finds the scripts and stylesheets which declare a
+// Subresource Integrity hash, for -check-sri.
+const sriSelector = "script[integrity][src], link[integrity][href]"
+
+// sriStrength ranks the hash algorithms browsers support for SRI. When
+// an integrity attribute lists more than one, only the strongest counts.
+var sriStrength = map[string]int{
+	"sha256": 1,
+	"sha384": 2,
+	"sha512": 3,
+}
+
+// subresources fetches the resources pages expect a hash for, and keeps
+// their hashes, so that a script every page includes is only downloaded
+// once.
+type subresources struct {
+	sync.Mutex
+	fetch  func(link string) ([]byte, error)
+	hashes map[string]map[string]string
+}
+
+// digests returns the base64 hash of the resource at link for each
+// algorithm in sriStrength.
+func (s *subresources) digests(link string) (map[string]string, error) {
+	s.Lock()
+	digests, ok := s.hashes[link]
+	s.Unlock()
+	if ok {
+		return digests, nil
+	}
+
+	data, err := s.fetch(link)
+	if err != nil {
+		return nil, err
+	}
+	sum256 := sha256.Sum256(data)
+	sum384 := sha512.Sum384(data)
+	sum512 := sha512.Sum512(data)
+	digests = map[string]string{
+		"sha256": base64.StdEncoding.EncodeToString(sum256[:]),
+		"sha384": base64.StdEncoding.EncodeToString(sum384[:]),
+		"sha512": base64.StdEncoding.EncodeToString(sum512[:]),
+	}
+
+	s.Lock()
+	s.hashes[link] = digests
+	s.Unlock()
+	return digests, nil
+}
+
+// sriMatches says whether a resource with the given digests passes an
+// integrity attribute, the way a browser decides whether to block it. An
+// attribute can list several hashes, each with options after a ?, and the
+// resource only has to match one of those using the strongest algorithm
+// listed. Hashes with algorithms we don't know are ignored, and browsers
+// don't block anything if that leaves none.
+func sriMatches(integrity string, digests map[string]string) bool {
+	strongest := 0
+	matched := false
+	for _, token := range strings.Fields(integrity) {
+		if i := strings.Index(token, "?"); i >= 0 {
+			token = token[:i]
+		}
+		i := strings.Index(token, "-")
+		if i < 0 {
+			continue
+		}
+		alg, digest := strings.ToLower(token[:i]), token[i+1:]
+		strength, ok := sriStrength[alg]
+		if !ok || strength < strongest {
+			continue
+		}
+		if strength > strongest {
+			strongest = strength
+			matched = false
+		}
+		if digest == digests[alg] {
+			matched = true
+		}
+	}
+	return strongest == 0 || matched
+}
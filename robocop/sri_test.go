@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+)
+
+func TestSRIMatches(t *testing.T) {
+	data := []byte("alert(1)")
+	sum256 := sha256.Sum256(data)
+	sum384 := sha512.Sum384(data)
+	good256 := "sha256-" + base64.StdEncoding.EncodeToString(sum256[:])
+	good384 := "sha384-" + base64.StdEncoding.EncodeToString(sum384[:])
+	digests := map[string]string{
+		"sha256": base64.StdEncoding.EncodeToString(sum256[:]),
+		"sha384": base64.StdEncoding.EncodeToString(sum384[:]),
+	}
+
+	tests := []struct {
+		name      string
+		integrity string
+		want      bool
+	}{
+		{name: "matches", integrity: good256, want: true},
+		{name: "doesn't match", integrity: "sha256-nope"},
+		{name: "with options", integrity: good384 + "?foo", want: true},
+		{name: "one of several", integrity: "sha384-nope " + good384, want: true},
+		{name: "only the strongest counts", integrity: good256 + " sha384-nope"},
+		{name: "unknown algorithms", integrity: "md5-nope", want: true},
+		{name: "nothing", integrity: "", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sriMatches(tt.integrity, digests); got != tt.want {
+				t.Errorf("%q: got %v, want %v", tt.integrity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSRISkipsIgnored(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/": html(`
+			<script src="/app.js" integrity="sha256-nope"></script>
+			<script src="/vendor/lib.js" integrity="sha256-nope"></script>`),
+		"http://example.com/app.js":        {status: 200, body: "app"},
+		"http://example.com/vendor/lib.js": {status: 200, body: "lib"},
+	})
+
+	opts := testOptions(t, "-check-sri")
+	opts.ignore = ignoring(t, "example.com/vendor/*")
+	_, rows := testCrawl(t, site, "http://example.com/", opts)
+
+	if got := site.count("GET", "http://example.com/vendor/lib.js"); got != 0 {
+		t.Errorf("got %d GETs of the ignored script, want none", got)
+	}
+	want := "http://example.com/app.js sri-mismatch"
+	if got := reported(rows); len(got) != 1 || got[0] != want {
+		t.Errorf("got report %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often -csv-stream is flushed to disk.
+const csvStreamFlushInterval = time.Second
+
+// csvStream writes failures to a CSV file as soon as we know about them,
+// rather than all at the end, so that a long crawl which dies part way
+// through still leaves a report behind. The rows have the same columns as
+// report.csv, but the HTTPS ones are left empty.
+type csvStream struct {
+	sync.Mutex
+	file *os.File
+	w    *csv.Writer
+	seen map[[2]string]bool
+	stop chan struct{}
+}
+
+func newCSVStream(path string) (*csvStream, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &csvStream{
+		file: file,
+		w:    csv.NewWriter(file),
+		seen: map[[2]string]bool{},
+		stop: make(chan struct{}),
+	}
+	_ = s.w.Write(reportHeader)
+	s.flush()
+
+	go func() {
+		ticker := time.NewTicker(csvStreamFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.flush()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// write adds a row, unless we've already written one for the same source
+// page and link.
+func (s *csvStream) write(row []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := [2]string{row[sourceColumn], row[linkColumn]}
+	if s.seen[key] {
+		return
+	}
+	s.seen[key] = true
+	_ = s.w.Write(row)
+}
+
+func (s *csvStream) flush() {
+	s.Lock()
+	s.w.Flush()
+	s.Unlock()
+}
+
+func (s *csvStream) Close() error {
+	close(s.stop)
+	s.flush()
+	if err := s.w.Error(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// streamRow writes the row for link on the page source to -csv-stream, if
+// we know it failed. The caller must hold the lock.
+func (res *results) streamRow(opts *options, source, link, checked string) {
+	_, status := res.linkStatus(link, checked)
+	if status == "" || acceptable(status, opts) || notChecked(status) {
+		return
+	}
+
+	row := make([]string, reportColumns)
+	row[sourceColumn] = source
+	row[linkColumn] = link
+	row[statusColumn] = status
+	row[locationColumn] = res.redirects[checked]
+	row[elementColumn] = res.pages[source][link]
+	row[severityColumn] = statusSeverity(status, opts)
+	row[requestIDColumn] = res.requestIDs[checked]
+	res.stream.write(row)
+}
+
+// streamLink streams the row for a link we've just found, in case we
+// already checked it from another page.
+func streamLink(res *results, opts *options, source, link string) {
+	if res.stream == nil {
+		return
+	}
+	res.Lock()
+	defer res.Unlock()
+	res.streamRow(opts, source, link, checkedURL(link, opts))
+}
+
+// streamChecked streams the rows for every link we've found so far which
+// was requested as one of checked, now that we know how it went. addLink
+// indexes the links by their URL without the fragment, which is what
+// checkedURL turns them into at most, so we only look at the ones which
+// could have been requested as u rather than every link on every page.
+func streamChecked(res *results, opts *options, checked ...string) {
+	if res.stream == nil {
+		return
+	}
+	res.Lock()
+	defer res.Unlock()
+
+	for _, u := range checked {
+		if _, status := res.linkStatus("", u); status == "" || acceptable(status, opts) {
+			continue
+		}
+		for _, found := range res.linkIndex[withoutFragment(u)] {
+			source, link := found[0], found[1]
+			if checkedURL(link, opts) == u {
+				res.streamRow(opts, source, link, u)
+			}
+		}
+	}
+}
+
+// withoutFragment returns link with any #fragment taken off.
+func withoutFragment(link string) string {
+	if i := strings.Index(link, "#"); i >= 0 {
+		return link[:i]
+	}
+	return link
+}
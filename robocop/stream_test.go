@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCSVStreamPartialRows(t *testing.T) {
+	tests := []struct {
+		name            string
+		links           [][2]string
+		heads           map[string]int
+		ignoreFragments bool
+		want            int
+	}{
+		{
+			name: "one failure on two pages",
+			links: [][2]string{
+				{"http://example.com/", "http://example.com/gone"},
+				{"http://example.com/about", "http://example.com/gone"},
+				{"http://example.com/about", "http://example.com/fine"},
+			},
+			heads: map[string]int{"http://example.com/gone": 404, "http://example.com/fine": 200},
+			want:  2,
+		},
+		{
+			name: "fragments are requested separately",
+			links: [][2]string{
+				{"http://example.com/", "http://example.com/gone"},
+				{"http://example.com/", "http://example.com/gone#top"},
+			},
+			heads: map[string]int{"http://example.com/gone": 404},
+			want:  1,
+		},
+		{
+			name: "unless we ignore them",
+			links: [][2]string{
+				{"http://example.com/", "http://example.com/gone"},
+				{"http://example.com/", "http://example.com/gone#top"},
+			},
+			heads:           map[string]int{"http://example.com/gone": 404},
+			ignoreFragments: true,
+			want:            2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stream.csv")
+			stream, err := newCSVStream(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+
+			opts := &options{ignoreFragments: tt.ignoreFragments}
+			res := newResults()
+			res.stream = stream
+			for _, link := range tt.links {
+				res.addLink(link[0], link[1], "a")
+			}
+			for link, status := range tt.heads {
+				res.heads[link] = status
+				streamChecked(res, opts, link)
+			}
+
+			// We never close the stream, as though the crawl died, so
+			// the rows only get there with the periodic flush.
+			var rows [][]string
+			deadline := time.Now().Add(3 * csvStreamFlushInterval)
+			for time.Now().Before(deadline) {
+				rows = readCSV(t, path)
+				if len(rows) == tt.want+1 {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+			if len(rows) != tt.want+1 {
+				t.Fatalf("got %d rows after the header, want %d: %v", len(rows)-1, tt.want, rows)
+			}
+			for _, row := range rows[1:] {
+				if row[statusColumn] != "404" {
+					t.Errorf("got status %q for %v, want 404", row[statusColumn], row[linkColumn])
+				}
+			}
+		})
+	}
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// summary is the crawl at a glance, for when the full report is too much.
+type summary struct {
+	pages        int
+	links        int
+	failures     int
+	errors       int
+	warnings     int
+	health       float64
+	totalBytes   int
+	largestPage  string
+	largestBytes int
+}
+
+// checkedLinks returns the status code of each distinct link we found on
+// a page and checked, by the URL we requested it as. Links which never got
+// a response, e.g. because of a dns-error, have a code of 0. res.heads
+// won't do for this, since it also has the pages we crawled, the URLs we
+// were redirected from and the https versions of http links.
+func checkedLinks(res *results, opts *options) map[string]int {
+	checked := map[string]int{}
+	for _, links := range res.pages {
+		for link := range links {
+			u := checkedURL(link, opts)
+			if res.heads[u] != 0 || res.errors[u] != "" {
+				checked[u] = res.heads[u]
+			}
+		}
+	}
+	return checked
+}
+
+func summarize(res *results, rows linkReport, opts *options) summary {
+	s := summary{
+		pages:    len(res.sizes),
+		links:    len(checkedLinks(res, opts)),
+		failures: len(rows),
+		health:   health(res, opts),
+	}
+
+	for _, row := range rows {
+		switch row[severityColumn] {
+		case severityError:
+			s.errors++
+		case severityWarning:
+			s.warnings++
+		}
+	}
+
+	for page, size := range res.sizes {
+		s.totalBytes += size
+		if size > s.largestBytes || (size == s.largestBytes && page < s.largestPage) {
+			s.largestPage = page
+			s.largestBytes = size
+		}
+	}
+	return s
+}
+
+func (s summary) averageBytes() int {
+	if s.pages == 0 {
+		return 0
+	}
+	return s.totalBytes / s.pages
+}
+
+func printSummary(w io.Writer, s summary) {
+	fmt.Fprintf(w, "pages crawled:    %d\n", s.pages)
+	fmt.Fprintf(w, "links checked:    %d\n", s.links)
+	fmt.Fprintf(w, "failures:         %d\n", s.failures)
+	fmt.Fprintf(w, "  errors:         %d\n", s.errors)
+	fmt.Fprintf(w, "  warnings:       %d\n", s.warnings)
+	fmt.Fprintf(w, "link health:      %.1f%%\n", s.health)
+	fmt.Fprintf(w, "bytes downloaded: %d\n", s.totalBytes)
+	fmt.Fprintf(w, "average page:     %d bytes\n", s.averageBytes())
+	if s.largestPage != "" {
+		fmt.Fprintf(w, "largest page:     %s (%d bytes)\n", s.largestPage, s.largestBytes)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestSummarizeLinksChecked(t *testing.T) {
+	tests := []struct {
+		name            string
+		links           []string
+		ignoreFragments bool
+		want            int
+	}{
+		{
+			name:  "distinct links",
+			links: []string{"http://example.com/a", "http://example.com/b"},
+			want:  2,
+		},
+		{
+			name:  "fragments are their own links",
+			links: []string{"http://example.com/a", "http://example.com/a#top"},
+			want:  2,
+		},
+		{
+			name:            "unless we ignore them",
+			links:           []string{"http://example.com/a", "http://example.com/a#top"},
+			ignoreFragments: true,
+			want:            1,
+		},
+		{
+			name:  "unchecked links don't count",
+			links: []string{"http://example.com/a", "http://example.com/never"},
+			want:  1,
+		},
+		{
+			name:  "links which never got a response do",
+			links: []string{"http://example.com/a", "http://nowhere.invalid/"},
+			want:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &options{ignoreFragments: tt.ignoreFragments}
+			res := newResults()
+			// The page itself, a redirect alias and an https probe are
+			// in res.heads too, but they aren't links on the page.
+			res.heads["http://example.com/"] = 200
+			res.heads["http://example.com/old"] = 301
+			res.heads["https://example.com/a"] = 200
+			res.heads["http://example.com/a"] = 200
+			res.heads["http://example.com/a#top"] = 200
+			res.heads["http://example.com/b"] = 404
+			res.errors["http://nowhere.invalid/"] = "dns-error"
+			for _, link := range tt.links {
+				res.addLink("http://example.com/", link, "a")
+			}
+
+			if got := summarize(res, nil, opts).links; got != tt.want {
+				t.Errorf("got %d links checked, want %d", got, tt.want)
+			}
+		})
+	}
+}
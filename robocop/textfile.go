@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writeTextfile writes the crawl's numbers in the Prometheus text format,
+// for node_exporter's textfile collector. We write to a temporary file and
+// rename it over path, so that node_exporter never sees half a file. The
+// temporary name doesn't end in .prom, so it's ignored in the meantime.
+func writeTextfile(path string, res *results, rows linkReport, opts *options) error {
+	s := summarize(res, rows, opts)
+
+	var b bytes.Buffer
+	gauge := func(name, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+	gauge("robocop_pages_crawled", "Pages crawled.", s.pages)
+	gauge("robocop_links_checked", "Links checked.", s.links)
+	gauge("robocop_failures", "Rows in the report.", s.failures)
+	gauge("robocop_errors", "Rows in the report with error severity.", s.errors)
+	gauge("robocop_warnings", "Rows in the report with warning severity.", s.warnings)
+	gauge("robocop_link_health_percent", "Percentage of checked links which returned 2xx.", s.health)
+	gauge("robocop_last_run_timestamp_seconds", "When the crawl finished.", time.Now().Unix())
+
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row[statusColumn]]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	b.WriteString("# HELP robocop_failures_by_status Rows in the report by status.\n")
+	b.WriteString("# TYPE robocop_failures_by_status gauge\n")
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "robocop_failures_by_status{status=\"%s\"} %d\n", escapeLabel(status), counts[status])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	// node_exporter usually runs as another user.
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// escapeLabel escapes a label value for the Prometheus text format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscapeLabel(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "404", want: "404"},
+		{value: `say "hi"`, want: `say \"hi\"`},
+		{value: `C:\path`, want: `C:\\path`},
+		{value: "two\nlines", want: `two\nlines`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := escapeLabel(tt.value); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	site := newStubSite(map[string]stubPage{
+		"http://example.com/":     html(`<a href="/gone">Gone</a><a href="/also-gone">Also gone</a><a href="/old">Old</a>`),
+		"http://example.com/gone": {status: 404},
+		"http://example.com/old":  {status: 301, location: "http://example.com/"},
+	})
+	opts := testOptions(t, "-follow-redirects=false")
+	res, rows := testCrawl(t, site, "http://example.com/", opts)
+
+	path := filepath.Join(t.TempDir(), "robocop.prom")
+	if err := os.WriteFile(path, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTextfile(path, res, rows, opts); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		// Every page on the site we fetched, broken or not.
+		"# TYPE robocop_pages_crawled gauge\nrobocop_pages_crawled 4\n",
+		"robocop_failures 3\n",
+		"robocop_errors 2\n",
+		"robocop_warnings 1\n",
+		"robocop_last_run_timestamp_seconds ",
+		`robocop_failures_by_status{status="301"} 1` + "\n" + `robocop_failures_by_status{status="404"} 2` + "\n",
+	}
+	for _, want := range tests {
+		t.Run(want, func(t *testing.T) {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%q isn't in\n%s", want, data)
+			}
+		})
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("got %v %v, want it readable by node_exporter", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("got %d files, want the temporary one gone", len(entries))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// isTextResource is true for Markdown and plain text files, going by the
+// content type or the extension. Plenty of servers send Markdown as
+// text/plain, or as nothing in particular, so the extension is the better
+// guide.
+func isTextResource(contentType, link string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "text/markdown") || strings.Contains(contentType, "text/x-markdown") {
+		return true
+	}
+	if strings.Contains(contentType, "text/html") {
+		return false
+	}
+	switch strings.ToLower(path.Ext(strings.SplitN(link, "?", 2)[0])) {
+	case ".md", ".markdown", ".txt":
+		return true
+	}
+	return false
+}
+
+var (
+	// [text](url "title") and ![alt](url)
+	markdownInlinePattern = regexp.MustCompile(`\]\(\s*<?([^()\s<>]+)>?(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	// [label]: url "title"
+	markdownReferencePattern = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:\s*<?([^\s<>]+)>?`)
+	// Anything else which looks like a URL, including <url> autolinks.
+	bareURLPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+)
+
+// textLinks returns the links in a Markdown or plain text file: Markdown
+// inline and reference links, which can be relative, and bare http(s)
+// URLs. Each link is only returned once.
+func textLinks(data []byte) []string {
+	links := []string{}
+	seen := map[string]bool{}
+	add := func(link string) {
+		// Sentences end with punctuation, URLs usually don't.
+		link = strings.TrimRight(link, ".,;:!?")
+		if link == "" || strings.HasPrefix(link, "#") || seen[link] {
+			return
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+
+	for _, pattern := range []*regexp.Regexp{markdownInlinePattern, markdownReferencePattern} {
+		for _, m := range pattern.FindAllSubmatch(data, -1) {
+			add(string(m[1]))
+		}
+	}
+	for _, m := range bareURLPattern.FindAll(data, -1) {
+		add(string(m))
+	}
+	return links
+}
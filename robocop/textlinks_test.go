@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsTextResource(t *testing.T) {
+	tests := []struct {
+		contentType string
+		link        string
+		want        bool
+	}{
+		{contentType: "text/markdown; charset=utf-8", link: "http://example.com/readme", want: true},
+		{contentType: "text/x-markdown", link: "http://example.com/readme", want: true},
+		{contentType: "text/plain", link: "http://example.com/README.md", want: true},
+		{link: "http://example.com/notes.markdown?raw=1", want: true},
+		{contentType: "text/plain", link: "http://example.com/robots.txt", want: true},
+		{contentType: "text/plain", link: "http://example.com/readme"},
+		{contentType: "text/html", link: "http://example.com/README.md"},
+		{link: "http://example.com/?file=README.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType+" "+tt.link, func(t *testing.T) {
+			if got := isTextResource(tt.contentType, tt.link); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "no links", text: "# Title\n\nJust words.", want: []string{}},
+		{
+			name: "inline",
+			text: `See [the docs](docs/index.md "Docs") and ![logo]( <img/logo.png> ).`,
+			want: []string{"docs/index.md", "img/logo.png"},
+		},
+		{
+			name: "reference",
+			text: "Read [the guide][guide].\n\n[guide]: https://example.com/guide 'Guide'\n   [other]: <../other.md>",
+			want: []string{"https://example.com/guide", "../other.md"},
+		},
+		{
+			name: "bare",
+			text: "Go to https://example.com/a. Or <https://example.com/b>, or (http://example.com/c)!",
+			want: []string{"https://example.com/a", "https://example.com/b", "http://example.com/c"},
+		},
+		{
+			name: "each once, without anchors",
+			text: "[a](https://example.com/a) https://example.com/a [top](#top)",
+			want: []string{"https://example.com/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := textLinks([]byte(tt.text)); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got links\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// hostTimeout is a request timeout for hosts matching a glob pattern, e.g.
+// *.slow-cdn.com=30s
+type hostTimeout struct {
+	pattern string
+	timeout time.Duration
+}
+
+// hostTimeouts implements flag.Value so that -host-timeout can be repeated
+// or given a comma separated list.
+type hostTimeouts []hostTimeout
+
+func (h *hostTimeouts) String() string {
+	pairs := make([]string, 0, len(*h))
+	for _, ht := range *h {
+		pairs = append(pairs, ht.pattern+"="+ht.timeout.String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h *hostTimeouts) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not in pattern=duration format", pair)
+		}
+		if _, err := path.Match(parts[0], ""); err != nil {
+			return fmt.Errorf("bad host pattern %q: %v", parts[0], err)
+		}
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return err
+		}
+		*h = append(*h, hostTimeout{pattern: parts[0], timeout: timeout})
+	}
+	return nil
+}
+
+// lookup returns the timeout for the first pattern matching host.
+func (h hostTimeouts) lookup(host string, fallback time.Duration) time.Duration {
+	for _, ht := range h {
+		if ok, _ := path.Match(ht.pattern, host); ok {
+			return ht.timeout
+		}
+	}
+	return fallback
+}
+
+// timeoutTransport applies a timeout to each request based on its host.
+// http.Client only knows about a single timeout for everything, so the
+// collector's client timeout is switched off and this does the job instead.
+type timeoutTransport struct {
+	transport http.RoundTripper
+	timeouts  hostTimeouts
+	fallback  time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := t.timeouts.lookup(req.URL.Hostname(), t.fallback)
+	if timeout <= 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The deadline needs to cover reading the body too.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostTimeouts(t *testing.T) {
+	var timeouts hostTimeouts
+	for _, value := range []string{"*.slow.example.com=30s", "api.example.com=5s,*.example.com=1s"} {
+		if err := timeouts.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		host string
+		want time.Duration
+	}{
+		{host: "cdn.slow.example.com", want: 30 * time.Second},
+		{host: "api.example.com", want: 5 * time.Second},
+		{host: "www.example.com", want: time.Second},
+		// Unlike a path, a host has no slashes for * to stop at.
+		{host: "a.cdn.example.com", want: time.Second},
+		{host: "example.com", want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := timeouts.lookup(tt.host, 10*time.Second); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostTimeoutsSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "example.com=5s"},
+		{value: "a.example.com=5s,b.example.com=1m"},
+		{value: "example.com", wantErr: true},
+		{value: "example.com=soon", wantErr: true},
+		{value: "[example.com=5s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var timeouts hostTimeouts
+			if err := timeouts.Set(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want one %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// hangingTransport doesn't answer until the request is cancelled.
+type hangingTransport struct{}
+
+func (hangingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	var timeouts hostTimeouts
+	if err := timeouts.Set("slow.test=10ms"); err != nil {
+		t.Fatal(err)
+	}
+	transport := &timeoutTransport{transport: hangingTransport{}, timeouts: timeouts, fallback: 20 * time.Millisecond}
+
+	for _, link := range []string{"http://slow.test/", "http://example.com/"} {
+		t.Run(link, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", link, nil)
+			if _, err := transport.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got error %v, want the deadline to pass", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tlsTransport looks at the TLS connection for the first https request to
+// each host, for -strict-tls, and calls report with anything wrong with
+// it: a certificate which expires within expiryWindow, a self-signed or
+// otherwise untrusted chain, or a protocol older than TLS 1.2.
+type tlsTransport struct {
+	sync.Mutex
+	transport    http.RoundTripper
+	expiryWindow time.Duration
+	report       func(Finding)
+	seen         map[string]bool
+}
+
+// strictTLSBase lets the base transport connect with TLS 1.0 and 1.1, so
+// that we can report them rather than just failing.
+func strictTLSBase(base http.RoundTripper) http.RoundTripper {
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.MinVersion = tls.VersionTLS10
+	return transport
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if req.URL.Scheme != "https" {
+		return resp, err
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	untrusted := err != nil && errors.As(err, &unknownAuthority)
+	if !untrusted && (err != nil || resp.TLS == nil) {
+		return resp, err
+	}
+
+	t.Lock()
+	seen := t.seen[req.URL.Host]
+	t.seen[req.URL.Host] = true
+	t.Unlock()
+	if seen {
+		return resp, err
+	}
+
+	link := req.URL.String()
+	if untrusted {
+		status := "tls-untrusted"
+		if cert := unknownAuthority.Cert; cert != nil && cert.CheckSignatureFrom(cert) == nil {
+			status = "tls-self-signed"
+		}
+		t.report(Finding{Page: link, Link: link, Status: status})
+		return resp, err
+	}
+
+	if resp.TLS.Version < tls.VersionTLS12 {
+		t.report(Finding{Page: link, Link: link, Status: "tls-weak-version"})
+	}
+	if certs := resp.TLS.PeerCertificates; len(certs) > 0 && time.Until(certs[0].NotAfter) < t.expiryWindow {
+		t.report(Finding{Page: link, Link: link, Status: "tls-expiring"})
+	}
+	return resp, err
+}
+
+// insecureHostsBase skips TLS verification for the given hosts, for
+// -allow-insecure-hosts, and verifies everything else as usual. A host can
+// be given with or without its port.
+func insecureHostsBase(base http.RoundTripper, hosts []string) http.RoundTripper {
+	transport, ok := base.(*http.Transport)
+	if !ok || len(hosts) == 0 {
+		return base
+	}
+	insecure := map[string]bool{}
+	for _, host := range hosts {
+		insecure[strings.ToLower(host)] = true
+	}
+
+	transport = transport.Clone()
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	config := transport.TLSClientConfig
+	if config == nil {
+		config = &tls.Config{}
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		c := config.Clone()
+		if c.ServerName == "" {
+			c.ServerName = host
+		}
+		// Dialing TLS ourselves means the transport no longer offers
+		// HTTP/2 for us.
+		if len(c.NextProtos) == 0 {
+			c.NextProtos = []string{"h2", "http/1.1"}
+		}
+		c.InsecureSkipVerify = insecure[strings.ToLower(addr)] || insecure[strings.ToLower(host)]
+		tlsConn := tls.Client(conn, c)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return transport
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSTransport(t *testing.T) {
+	// httptest's certificate is self-signed, and good until 2084.
+	tests := []struct {
+		name    string
+		server  func(*tls.Config)
+		trusted bool
+		window  time.Duration
+		want    []string
+	}{
+		{name: "fine", trusted: true, window: 30 * 24 * time.Hour, want: []string{}},
+		{name: "expiring", trusted: true, window: 100 * 365 * 24 * time.Hour, want: []string{"tls-expiring"}},
+		{name: "self-signed", want: []string{"tls-self-signed"}},
+		{
+			name:    "TLS 1.1",
+			server:  func(c *tls.Config) { c.MinVersion, c.MaxVersion = tls.VersionTLS10, tls.VersionTLS11 },
+			trusted: true,
+			want:    []string{"tls-weak-version"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.Config.ErrorLog = log.New(io.Discard, "", 0)
+			server.TLS = &tls.Config{}
+			if tt.server != nil {
+				tt.server(server.TLS)
+			}
+			server.StartTLS()
+			defer server.Close()
+
+			base := http.RoundTripper(&http.Transport{})
+			if tt.trusted {
+				base = server.Client().Transport
+			}
+			got := []string{}
+			transport := &tlsTransport{
+				transport:    strictTLSBase(base),
+				expiryWindow: tt.window,
+				report:       func(f Finding) { got = append(got, f.Status) },
+				seen:         map[string]bool{},
+			}
+
+			// Each host is only looked at once.
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest("HEAD", server.URL+"/", nil)
+				if resp, err := transport.RoundTrip(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsecureHostsBase(t *testing.T) {
+	// Both servers have httptest's self-signed certificate.
+	servers := map[string]*httptest.Server{}
+	for _, name := range []string{"listed", "unlisted"} {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Config.ErrorLog = log.New(io.Discard, "", 0)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+		servers[name] = server
+	}
+	listed := strings.TrimPrefix(servers["listed"].URL, "https://")
+
+	tests := []struct {
+		name   string
+		server string
+		hosts  []string
+		ok     bool
+	}{
+		{name: "listed host", server: "listed", hosts: []string{listed}, ok: true},
+		{name: "listed host in upper case", server: "listed", hosts: []string{strings.ToUpper(listed)}, ok: true},
+		{name: "unlisted host", server: "unlisted", hosts: []string{listed}},
+		{name: "nothing listed", server: "listed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: insecureHostsBase(http.DefaultTransport.(*http.Transport).Clone(), tt.hosts)}
+			resp, err := client.Get(servers[tt.server].URL + "/")
+			if !tt.ok {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("got a response, want a certificate error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v, want a response", err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != 2 {
+				t.Errorf("got %s, want HTTP/2", resp.Proto)
+			}
+		})
+	}
+}
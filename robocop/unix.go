@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// unixSocketTransport sends requests for the hosts we're crawling to the
+// Unix socket at socketPath, for services which aren't listening on TCP.
+// Requests for any other host go out over the network as usual.
+func unixSocketTransport(socketPath string, hosts []string) *http.Transport {
+	// The dialer sees host:port, so add the default ports where the hosts
+	// left them out.
+	addrs := map[string]bool{}
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			addrs[host] = true
+		} else {
+			addrs[net.JoinHostPort(host, "80")] = true
+			addrs[net.JoinHostPort(host, "443")] = true
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addrs[addr] {
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return transport
+}
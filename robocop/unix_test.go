@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketTransport(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot listen on a Unix socket: %v", err)
+	}
+	onSocket := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "socket")
+	})}
+	go func() { _ = onSocket.Serve(listener) }()
+	defer onSocket.Close()
+
+	onTCP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "tcp")
+	}))
+	defer onTCP.Close()
+
+	client := &http.Client{Transport: unixSocketTransport(socket, []string{"example.com", "app.test:8080"})}
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "http://example.com/", want: "socket"},
+		{link: "http://example.com:80/", want: "socket"},
+		{link: "http://app.test:8080/", want: "socket"},
+		{link: onTCP.URL, want: "tcp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			resp, err := client.Get(tt.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("got an answer from %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validateLinks runs -validate-cmd for each of the given links we checked,
+// with the URL and its status as the last two arguments. A command which
+// exits 0 leaves the link alone. Otherwise the first line it printed, or
+// invalid if it printed nothing, becomes the link's status.
+func validateLinks(res *results, found []string, opts *options) {
+	args := strings.Fields(opts.validateCmd)
+	if len(args) == 0 {
+		return
+	}
+
+	statuses := map[string]string{}
+	for _, link := range found {
+		checked := checkedURL(link, opts)
+		if _, status := res.linkStatus(link, checked); status != "" && !notChecked(status) {
+			statuses[checked] = status
+		}
+	}
+	links := make([]string, 0, len(statuses))
+	for link := range statuses {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+
+	parallel := opts.validateParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	slots := make(chan bool, parallel)
+	var wg sync.WaitGroup
+	for _, link := range links {
+		wg.Add(1)
+		slots <- true
+		go func(link, status string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			verdict := runValidateCmd(args, link, status, opts)
+			if verdict == "" {
+				return
+			}
+			if opts.verbose {
+				log.Printf("%v says %v is %v", args[0], link, verdict)
+			}
+			res.Lock()
+			res.invalid[link] = verdict
+			res.Unlock()
+		}(link, statuses[link])
+	}
+	wg.Wait()
+}
+
+// foundLinks returns every link found on the pages we crawled.
+func foundLinks(res *results) []string {
+	links := []string{}
+	for _, found := range res.pages {
+		for link := range found {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// runValidateCmd returns the status the command gives link, or "" if it
+// says the link is fine.
+func runValidateCmd(args []string, link, status string, opts *options) string {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.validateTimeout)
+	defer cancel()
+
+	cmdArgs := append(append([]string{}, args[1:]...), link, status)
+	cmd := exec.CommandContext(ctx, args[0], cmdArgs...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	// Killing a shell script leaves anything it started holding stdout
+	// open, so don't wait for that once the timeout is up.
+	cmd.WaitDelay = 100 * time.Millisecond
+	err := cmd.Run()
+	if err == nil {
+		return ""
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return "validate-timeout"
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		if opts.verbose {
+			log.Printf("cannot run %v for %v because %v", args[0], link, err)
+		}
+		return "validate-error"
+	}
+
+	line, _ := bufio.NewReader(&stdout).ReadString('\n')
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+	return "invalid"
+}
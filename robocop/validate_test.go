@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunValidateCmd(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "fine", script: `test "$1" = http://example.com/ && test "$2" = 200`},
+		{name: "first line", script: `echo " bad-title "; echo more; exit 1`, want: "bad-title"},
+		{name: "no output", script: `exit 3`, want: "invalid"},
+		{name: "too slow", script: `sleep 5`, want: "validate-timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &options{validateTimeout: 200 * time.Millisecond}
+			args := []string{"sh", "-c", tt.script, "validate"}
+			if got := runValidateCmd(args, "http://example.com/", "200", opts); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	opts := &options{validateTimeout: time.Second}
+	if got := runValidateCmd([]string{filepath.Join(t.TempDir(), "missing")}, "http://example.com/", "200", opts); got != "validate-error" {
+		t.Errorf("got %q for a command which doesn't exist, want validate-error", got)
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	// Links whose path has "bad" in it are invalid.
+	script := filepath.Join(t.TempDir(), "validate.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncase \"$1\" in *bad*) echo \"bad-$2\"; exit 1;; esac\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cmd  string
+		want map[string]string
+	}{
+		{name: "no command", want: map[string]string{}},
+		{
+			// Links we skipped aren't validated.
+			name: "with a command",
+			cmd:  script,
+			want: map[string]string{
+				"http://example.com/bad":  "bad-200",
+				"http://example.com/bad2": "bad-404",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, "-validate-cmd="+tt.cmd, "-validate-parallel=2")
+			res := newResults()
+			for link, status := range map[string]int{
+				"http://example.com/good": 200,
+				"http://example.com/bad":  200,
+				"http://example.com/bad2": 404,
+			} {
+				res.addLink("http://example.com/", link, "a")
+				res.heads[link] = status
+			}
+			res.addLink("http://example.com/", "mailto:bad@example.com", "a")
+
+			validateLinks(res, foundLinks(res), opts)
+			if len(res.invalid) != len(tt.want) {
+				t.Errorf("got %v, want %v", res.invalid, tt.want)
+			}
+			for link, want := range tt.want {
+				if got := res.invalid[link]; got != want {
+					t.Errorf("%s: got %q, want %q", link, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/bits"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// warmupTransport staggers the first requests to each host, for
+// -connection-warmup, so that a crawl with plenty of parallelism doesn't
+// open all of its connections at once. Requests go out in waves a step
+// apart, each twice the size of the last: one, then two, then four and so
+// on, with a little jitter so that they don't line up. Once the waves have
+// caught up with the clock, requests are made straight away.
+//
+// It holds back requests rather than dials, so that it can sit outside the
+// timeouts, as rampUpTransport does, and waiting for a wave doesn't count
+// against -timeout. While we're warming up, nearly every request to a host
+// needs a connection of its own anyway.
+type warmupTransport struct {
+	sync.Mutex
+	transport http.RoundTripper
+	step      time.Duration
+	hosts     map[string]*warmupHost
+}
+
+type warmupHost struct {
+	first    time.Time
+	requests int
+}
+
+func newWarmupTransport(transport http.RoundTripper, step time.Duration) *warmupTransport {
+	return &warmupTransport{transport: transport, step: step, hosts: map[string]*warmupHost{}}
+}
+
+// delay returns how long the next request to host should wait, if it were
+// made at now.
+func (t *warmupTransport) delay(host string, now time.Time) time.Duration {
+	t.Lock()
+	defer t.Unlock()
+
+	h, ok := t.hosts[host]
+	if !ok {
+		h = &warmupHost{first: now}
+		t.hosts[host] = h
+	}
+	n := h.requests
+	h.requests++
+	if n == 0 {
+		return 0
+	}
+
+	// Request n, counting from 0, is in wave bits.Len(n): 1 is in wave 1,
+	// 2 and 3 in wave 2, 4 to 7 in wave 3.
+	wave := time.Duration(bits.Len(uint(n)))
+	jitter := time.Duration(rand.Int63n(int64(t.step/2) + 1))
+	at := h.first.Add(wave*t.step + jitter)
+	if wait := at.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (t *warmupTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if wait := t.delay(req.URL.Host, time.Now()); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
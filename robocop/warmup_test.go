@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWarmupDelay(t *testing.T) {
+	step := 100 * time.Millisecond
+	start := time.Now()
+
+	// The jitter is up to half a step, so each request waits for its wave
+	// and at most half a step more.
+	tests := []struct {
+		request int
+		wave    time.Duration
+	}{
+		{request: 0, wave: 0},
+		{request: 1, wave: 1},
+		{request: 2, wave: 2},
+		{request: 3, wave: 2},
+		{request: 4, wave: 3},
+		{request: 7, wave: 3},
+		{request: 8, wave: 4},
+	}
+
+	w := newWarmupTransport(nil, step)
+	n := 0
+	for _, tt := range tests {
+		var got time.Duration
+		for ; n <= tt.request; n++ {
+			got = w.delay("example.com", start)
+		}
+		if tt.wave == 0 {
+			if got != 0 {
+				t.Errorf("request %d: got a delay of %v, want none", tt.request, got)
+			}
+			continue
+		}
+		if min, max := tt.wave*step, tt.wave*step+step/2; got < min || got > max {
+			t.Errorf("request %d: got a delay of %v, want %v to %v", tt.request, got, min, max)
+		}
+	}
+
+	if got := w.delay("other.test", start); got != 0 {
+		t.Errorf("got a delay of %v for another host's first request, want none", got)
+	}
+	if got := w.delay("example.com", start.Add(time.Hour)); got != 0 {
+		t.Errorf("got a delay of %v once the waves have caught up, want none", got)
+	}
+}
+
+func TestWarmupOutsideTimeouts(t *testing.T) {
+	site := newStubSite(map[string]stubPage{"http://example.com/": {status: 200}})
+	// The later waves wait far longer than the timeout, which mustn't
+	// count against them.
+	transport := newWarmupTransport(&timeoutTransport{transport: site, fallback: 50 * time.Millisecond}, 100*time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	begin := time.Now()
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			resp, err := transport.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("request %d: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(begin); elapsed < 200*time.Millisecond {
+		t.Errorf("four requests took %v, want at least two waves", elapsed)
+	}
+}
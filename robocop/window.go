@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// crawlWindow implements flag.Value for -crawl-window, the hours of the day
+// we're allowed to make requests in, e.g. 22:00-06:00. A window which ends
+// earlier than it starts runs past midnight. Times are local.
+type crawlWindow struct {
+	set        bool
+	start, end time.Duration
+}
+
+func (w *crawlWindow) String() string {
+	if !w.set {
+		return ""
+	}
+	return formatClock(w.start) + "-" + formatClock(w.end)
+}
+
+func (w *crawlWindow) Set(value string) error {
+	var startH, startM, endH, endM int
+	if _, err := fmt.Sscanf(value, "%d:%d-%d:%d", &startH, &startM, &endH, &endM); err != nil {
+		return fmt.Errorf("%q is not in HH:MM-HH:MM format", value)
+	}
+	for _, t := range [][2]int{{startH, startM}, {endH, endM}} {
+		if t[0] < 0 || t[0] > 23 || t[1] < 0 || t[1] > 59 {
+			return fmt.Errorf("%q has a time which doesn't exist", value)
+		}
+	}
+	w.set = true
+	w.start = time.Duration(startH)*time.Hour + time.Duration(startM)*time.Minute
+	w.end = time.Duration(endH)*time.Hour + time.Duration(endM)*time.Minute
+	return nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// until returns how long it is from now until the window opens, or 0 if
+// it's open. A window which starts and ends at the same time never closes.
+func (w *crawlWindow) until(now time.Time) time.Duration {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	clock := now.Sub(midnight)
+
+	var open bool
+	switch {
+	case w.start == w.end:
+		open = true
+	case w.start < w.end:
+		open = clock >= w.start && clock < w.end
+	default:
+		open = clock >= w.start || clock < w.end
+	}
+	if open {
+		return 0
+	}
+
+	opens := midnight.Add(w.start)
+	if !opens.After(now) {
+		opens = midnight.AddDate(0, 0, 1).Add(w.start)
+	}
+	return opens.Sub(now)
+}
+
+// windowTransport holds requests back while we're outside the
+// -crawl-window, and lets them go once it opens again. Requests which have
+// already started are left to finish.
+type windowTransport struct {
+	transport http.RoundTripper
+	window    *crawlWindow
+	now       func() time.Time
+
+	mu     sync.Mutex
+	paused bool
+}
+
+func (t *windowTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		wait := t.window.until(t.now())
+		t.mu.Lock()
+		if wait > 0 && !t.paused {
+			log.Printf("pausing the crawl for %v, until the -crawl-window %v opens", wait.Round(time.Second), t.window)
+		}
+		t.paused = wait > 0
+		t.mu.Unlock()
+		if wait == 0 {
+			return t.transport.RoundTrip(req)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
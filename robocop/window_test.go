@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCrawlWindowSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "22:00-06:00", want: "22:00-06:00"},
+		{value: "9:30-17:05", want: "09:30-17:05"},
+		{value: "00:00-00:00", want: "00:00-00:00"},
+		{value: "22:00", wantErr: true},
+		{value: "nights", wantErr: true},
+		{value: "24:00-06:00", wantErr: true},
+		{value: "22:00-06:60", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var w crawlWindow
+			err := w.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one %v", err, tt.wantErr)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlWindowUntil(t *testing.T) {
+	at := func(clock string) time.Time {
+		now, err := time.Parse("2006-01-02 15:04", "2026-03-14 "+clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return now
+	}
+
+	tests := []struct {
+		window string
+		now    string
+		want   time.Duration
+	}{
+		{window: "09:00-17:00", now: "12:00", want: 0},
+		{window: "09:00-17:00", now: "09:00", want: 0},
+		{window: "09:00-17:00", now: "17:00", want: 16 * time.Hour},
+		{window: "09:00-17:00", now: "08:30", want: 30 * time.Minute},
+		{window: "22:00-06:00", now: "23:00", want: 0},
+		{window: "22:00-06:00", now: "05:59", want: 0},
+		{window: "22:00-06:00", now: "06:00", want: 16 * time.Hour},
+		{window: "22:00-06:00", now: "21:45", want: 15 * time.Minute},
+		{window: "03:00-03:00", now: "12:00", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window+" at "+tt.now, func(t *testing.T) {
+			var w crawlWindow
+			if err := w.Set(tt.window); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.until(at(tt.now)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowTransport(t *testing.T) {
+	var w crawlWindow
+	if err := w.Set("09:00-17:00"); err != nil {
+		t.Fatal(err)
+	}
+	site := newStubSite(map[string]stubPage{"http://example.com/": {status: 200}})
+
+	tests := []struct {
+		name    string
+		now     string
+		wantErr error
+	}{
+		{name: "open", now: "2026-03-14 12:00"},
+		{name: "closed", now: "2026-03-14 20:00", wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse("2006-01-02 15:04", tt.now)
+			transport := &windowTransport{transport: site, window: &w, now: func() time.Time { return now }}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+			resp, err := transport.RoundTrip(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+		})
+	}
+}